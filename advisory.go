@@ -0,0 +1,119 @@
+package quirk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+)
+
+const (
+	advisoryLockQuery        = "SELECT pg_advisory_lock(@key)"
+	advisoryTryLockQuery     = "SELECT pg_try_advisory_lock(@key)"
+	advisoryUnlockQuery      = "SELECT pg_advisory_unlock(@key)"
+	advisoryXactLockQuery    = "SELECT pg_advisory_xact_lock(@key)"
+	advisoryTryXactLockQuery = "SELECT pg_try_advisory_xact_lock(@key)"
+)
+
+func (d *DB) WithAdvisoryLock(ctx context.Context, key any, fn func() error) error {
+	return d.Session(
+		ctx, func(s *DB) error {
+			if err := s.Q(advisoryLockQuery, Map{"key": AdvisoryLockKey(key)}).Context(ctx).Exec(); err != nil {
+				return err
+			}
+			defer func() {
+				_ = s.advisoryUnlock(key)
+			}()
+			return fn()
+		},
+	)
+}
+
+func (d *DB) TryAdvisoryLock(ctx context.Context, key any, fn func() error) (bool, error) {
+	var acquired bool
+	err := d.Session(
+		ctx, func(s *DB) error {
+			var err error
+			acquired, err = s.tryAdvisoryLock(ctx, advisoryTryLockQuery, key)
+			if err != nil || !acquired {
+				return err
+			}
+			defer func() {
+				_ = s.advisoryUnlock(key)
+			}()
+			return fn()
+		},
+	)
+	return acquired, err
+}
+
+func (d *DB) WithAdvisoryXactLock(ctx context.Context, key any, fn func(tx *DB) error) error {
+	return d.inAdvisoryTransaction(
+		ctx, func(tx *DB) error {
+			if err := tx.Q(advisoryXactLockQuery, Map{"key": AdvisoryLockKey(key)}).Context(ctx).Exec(); err != nil {
+				return err
+			}
+			return fn(tx)
+		},
+	)
+}
+
+func (d *DB) TryAdvisoryXactLock(ctx context.Context, key any, fn func(tx *DB) error) (bool, error) {
+	var acquired bool
+	err := d.inAdvisoryTransaction(
+		ctx, func(tx *DB) error {
+			var err error
+			acquired, err = tx.tryAdvisoryLock(ctx, advisoryTryXactLockQuery, key)
+			if err != nil || !acquired {
+				return err
+			}
+			return fn(tx)
+		},
+	)
+	return acquired, err
+}
+
+func AdvisoryLockKey(key any) int64 {
+	v := reflect.ValueOf(key)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(v.Uint())
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(fmt.Sprintf("%v", key)))
+	return int64(h.Sum64())
+}
+
+func (d *DB) inAdvisoryTransaction(ctx context.Context, fn func(tx *DB) error) error {
+	if d.transaction {
+		if d.conn == nil {
+			return ErrorUnpinnedTransaction
+		}
+		return fn(d)
+	}
+	return d.Session(
+		ctx, func(s *DB) error {
+			tx, err := s.Begin()
+			if err != nil {
+				return err
+			}
+			if err := fn(tx); err != nil {
+				return errors.Join(err, tx.Rollback())
+			}
+			return tx.Commit()
+		},
+	)
+}
+
+func (d *DB) tryAdvisoryLock(ctx context.Context, query string, key any) (bool, error) {
+	var acquired bool
+	err := d.Q(query, Map{"key": AdvisoryLockKey(key)}).Context(ctx).Exec(&acquired)
+	return acquired, err
+}
+
+func (d *DB) advisoryUnlock(key any) error {
+	return d.Q(advisoryUnlockQuery, Map{"key": AdvisoryLockKey(key)}).Exec()
+}
@@ -0,0 +1,142 @@
+package quirk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvisoryLock(t *testing.T) {
+	createDB := func(t *testing.T) (*DB, sqlmock.Sqlmock) {
+		sqlDB, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+		return wrapConnection(sqlDB, Postgres), mock
+	}
+	key := AdvisoryLockKey("cron:cleanup")
+	t.Run(
+		"keys", func(t *testing.T) {
+			assert.Equal(t, int64(42), AdvisoryLockKey(42))
+			assert.Equal(t, key, AdvisoryLockKey("cron:cleanup"))
+			assert.NotEqual(t, key, AdvisoryLockKey("cron:report"))
+			type lockId uint16
+			for _, k := range []any{int8(5), int16(5), int32(5), uint(5), uint8(5), uint16(5), uint64(5), lockId(5)} {
+				assert.Equal(t, int64(5), AdvisoryLockKey(k))
+			}
+		},
+	)
+	t.Run(
+		"session lock", func(t *testing.T) {
+			db, mock := createDB(t)
+			mock.ExpectQuery(`SELECT pg_advisory_lock\(\$1\);`).WithArgs(key).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(`SELECT pg_advisory_unlock\(\$1\);`).WithArgs(key).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectExec(postgresDiscardAll).WillReturnResult(sqlmock.NewResult(0, 0))
+			called := false
+			assert.Nil(
+				t, db.WithAdvisoryLock(
+					context.Background(), "cron:cleanup", func() error {
+						called = true
+						return nil
+					},
+				),
+			)
+			assert.True(t, called)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"try lock not acquired", func(t *testing.T) {
+			db, mock := createDB(t)
+			mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\);`).
+				WithArgs(key).
+				WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(false))
+			mock.ExpectExec(postgresDiscardAll).WillReturnResult(sqlmock.NewResult(0, 0))
+			acquired, err := db.TryAdvisoryLock(
+				context.Background(), "cron:cleanup", func() error {
+					t.Fatal("must not run without lock")
+					return nil
+				},
+			)
+			assert.Nil(t, err)
+			assert.False(t, acquired)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"transaction lock requires pinned connection", func(t *testing.T) {
+			db, mock := createDB(t)
+			mock.ExpectQuery(`BEGIN;`).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(`ROLLBACK;`).WillReturnRows(sqlmock.NewRows(nil))
+			tx := db.MustBegin()
+			err := tx.WithAdvisoryXactLock(
+				context.Background(), "cron:cleanup", func(tx *DB) error {
+					t.Fatal("must not run without pinned connection")
+					return nil
+				},
+			)
+			assert.ErrorIs(t, err, ErrorUnpinnedTransaction)
+			_, err = tx.TryAdvisoryXactLock(
+				context.Background(), "cron:cleanup", func(tx *DB) error {
+					return nil
+				},
+			)
+			assert.ErrorIs(t, err, ErrorUnpinnedTransaction)
+			tx.MustRollback()
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"transaction lock rolled back on error", func(t *testing.T) {
+			db, mock := createDB(t)
+			failure := errors.New("failure")
+			mock.ExpectQuery(`BEGIN;`).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock\(\$1\);`).
+				WithArgs(key).
+				WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+			mock.ExpectQuery(`ROLLBACK;`).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectExec(postgresDiscardAll).WillReturnResult(sqlmock.NewResult(0, 0))
+			acquired, err := db.TryAdvisoryXactLock(
+				context.Background(), "cron:cleanup", func(tx *DB) error {
+					assert.True(t, tx.transaction)
+					return failure
+				},
+			)
+			assert.True(t, acquired)
+			assert.ErrorIs(t, err, failure)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"leader elector", func(t *testing.T) {
+			db, mock := createDB(t)
+			mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\);`).
+				WithArgs(key).
+				WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+			mock.ExpectQuery(`SELECT pg_advisory_unlock\(\$1\);`).WithArgs(key).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectExec(postgresDiscardAll).WillReturnResult(sqlmock.NewResult(0, 0))
+			ctx, cancel := context.WithCancel(context.Background())
+			lost := false
+			var leaderCtx context.Context
+			elector := NewLeaderElector(db, "cron:cleanup", time.Millisecond).
+				OnGained(
+					func(ctx context.Context) {
+						leaderCtx = ctx
+						cancel()
+					},
+				).
+				OnLost(
+					func() {
+						lost = true
+					},
+				)
+			assert.Nil(t, elector.Run(ctx))
+			assert.True(t, lost)
+			assert.False(t, elector.IsLeader())
+			assert.NotNil(t, leaderCtx.Err())
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+}
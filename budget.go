@@ -0,0 +1,138 @@
+package quirk
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type QueryBudget struct {
+	limit    int
+	mu       sync.Mutex
+	counts   map[string]int
+	total    int
+	duration time.Duration
+	onExceed func(fingerprint string, count int)
+}
+
+type queryBudgetKey struct{}
+
+const (
+	DefaultQueryBudgetLimit = 10
+)
+
+const (
+	HeaderQueryCount    = "X-Query-Count"
+	HeaderQueryDuration = "X-Query-Duration"
+)
+
+func WithQueryBudget(ctx context.Context, limit ...int) context.Context {
+	l := DefaultQueryBudgetLimit
+	if len(limit) > 0 {
+		l = limit[0]
+	}
+	return context.WithValue(
+		ctx, queryBudgetKey{}, &QueryBudget{
+			limit:  l,
+			counts: make(map[string]int),
+		},
+	)
+}
+
+func GetQueryBudget(ctx context.Context) *QueryBudget {
+	if ctx == nil {
+		return nil
+	}
+	budget, _ := ctx.Value(queryBudgetKey{}).(*QueryBudget)
+	return budget
+}
+
+func (b *QueryBudget) OnExceed(fn func(fingerprint string, count int)) *QueryBudget {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onExceed = fn
+	return b
+}
+
+func (b *QueryBudget) Limit() int {
+	return b.limit
+}
+
+func (b *QueryBudget) Total() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.total
+}
+
+func (b *QueryBudget) Duration() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.duration
+}
+
+func (b *QueryBudget) Counts() map[string]int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return maps.Clone(b.counts)
+}
+
+func (b *QueryBudget) Exceeded() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	result := make([]string, 0)
+	for fingerprint, count := range b.counts {
+		if count > b.limit {
+			result = append(result, fingerprint)
+		}
+	}
+	return result
+}
+
+func (b *QueryBudget) SetHeaders(header http.Header) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	header.Set(HeaderQueryCount, strconv.Itoa(b.total))
+	header.Set(HeaderQueryDuration, b.duration.String())
+}
+
+func (b *QueryBudget) record(fingerprint string, duration time.Duration) (int, bool, func(string, int)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.total++
+	b.duration += duration
+	b.counts[fingerprint]++
+	count := b.counts[fingerprint]
+	return count, count == b.limit+1, b.onExceed
+}
+
+func (d *DB) checkQueryBudget(ctx context.Context, event QueryEvent) {
+	budget := GetQueryBudget(ctx)
+	if budget == nil {
+		return
+	}
+	fingerprint, _ := Fingerprint(event.Query)
+	count, exceeded, onExceed := budget.record(fingerprint, event.Duration)
+	if !exceeded {
+		return
+	}
+	if d.logger != nil {
+		caller := event.Caller
+		if len(caller) == 0 {
+			caller = getCaller()
+		}
+		d.logger.LogAttrs(
+			ctx, slog.LevelWarn, "query budget exceeded",
+			slog.String("fingerprint", fingerprint),
+			slog.Int("count", count),
+			slog.Int("limit", budget.limit),
+			slog.String("caller", caller),
+		)
+	}
+	if onExceed != nil {
+		onExceed(fingerprint, count)
+	}
+}
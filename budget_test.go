@@ -0,0 +1,43 @@
+package quirk
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryBudget(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	db := wrapConnection(sqlDB, Postgres)
+	ctx := WithQueryBudget(context.Background(), 2)
+	budget := GetQueryBudget(ctx)
+	violations := make(map[string]int)
+	budget.OnExceed(
+		func(fingerprint string, count int) {
+			violations[fingerprint] = count
+		},
+	)
+	for id := 1; id <= 4; id++ {
+		q := db.Q(`SELECT name FROM tests WHERE id = @id`, Map{"id": id}).Context(ctx)
+		mock.ExpectQuery(q.CreateMatcher()).WithArgs(id).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+		assert.Nil(t, q.Exec())
+	}
+	other := db.Q(`SELECT count(*) FROM tests`).Context(ctx)
+	mock.ExpectQuery(other.CreateMatcher()).WillReturnRows(sqlmock.NewRows([]string{"count"}))
+	assert.Nil(t, other.Exec())
+	untracked := db.Q(`SELECT count(*) FROM tests`)
+	mock.ExpectQuery(untracked.CreateMatcher()).WillReturnRows(sqlmock.NewRows([]string{"count"}))
+	assert.Nil(t, untracked.Exec())
+	assert.Equal(t, map[string]int{"select name from tests where id = ?": 3}, violations)
+	assert.Equal(t, 5, budget.Total())
+	assert.Equal(t, []string{"select name from tests where id = ?"}, budget.Exceeded())
+	assert.Equal(t, 1, budget.Counts()["select count(*) from tests"])
+	header := make(http.Header)
+	budget.SetHeaders(header)
+	assert.Equal(t, "5", header.Get(HeaderQueryCount))
+	assert.Nil(t, GetQueryBudget(context.Background()))
+}
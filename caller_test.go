@@ -0,0 +1,54 @@
+package quirk_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/creamsensation/quirk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaller(t *testing.T) {
+	_, mock, err := sqlmock.NewWithDSN("quirk_caller")
+	assert.Nil(t, err)
+	db, err := quirk.Open("sqlmock", "quirk_caller")
+	assert.Nil(t, err)
+	db.Comment()
+	var event quirk.QueryEvent
+	db.Subscribe(
+		func(e quirk.QueryEvent) {
+			event = e
+		},
+	)
+	mock.ExpectQuery(`^/\*caller='.+TestCaller',file='caller_test.go%3A[0-9]+'\*/ SELECT 1;$`).
+		WillReturnRows(sqlmock.NewRows(nil))
+	assert.Nil(t, db.Q(`SELECT 1`).Exec())
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.True(t, strings.HasPrefix(event.Caller, "github.com/creamsensation/quirk_test.TestCaller "))
+	assert.Contains(t, event.Caller, "caller_test.go:")
+}
+
+func TestBudgetCaller(t *testing.T) {
+	_, mock, err := sqlmock.NewWithDSN("quirk_budget_caller")
+	assert.Nil(t, err)
+	db, err := quirk.Open("sqlmock", "quirk_budget_caller")
+	assert.Nil(t, err)
+	buf := new(bytes.Buffer)
+	db.Logger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	ctx := quirk.WithQueryBudget(context.Background(), 1)
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(`SELECT 1;`).WillReturnRows(sqlmock.NewRows(nil))
+		assert.Nil(t, db.Q(`SELECT 1`).Context(ctx).Exec())
+	}
+	assert.Nil(t, mock.ExpectationsWereMet())
+	record := make(map[string]any)
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "query budget exceeded", record["msg"])
+	caller, _ := record["caller"].(string)
+	assert.True(t, strings.HasPrefix(caller, "github.com/creamsensation/quirk_test.TestBudgetCaller "))
+}
@@ -0,0 +1,136 @@
+package quirk
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type ReplicaPolicy int
+
+type cluster struct {
+	replicas []*replica
+	next     atomic.Uint64
+	downtime time.Duration
+}
+
+type replica struct {
+	*DB
+	downUntil atomic.Int64
+}
+
+const (
+	RoundRobin ReplicaPolicy = iota
+	LeastConnections
+)
+
+const (
+	defaultReplicaDowntime = 5 * time.Second
+)
+
+var (
+	lockingClauses = []string{"for update", "for no key update", "for share", "for key share"}
+)
+
+func ConnectCluster(primary Config, replicas ...Config) (*DB, error) {
+	db, err := Connect(primary)
+	if err != nil {
+		return nil, err
+	}
+	replicaDBs := make([]*DB, 0, len(replicas))
+	for _, r := range replicas {
+		replicaDB, err := Connect(r)
+		if err != nil {
+			_ = db.Close()
+			for _, item := range replicaDBs {
+				_ = item.Close()
+			}
+			return nil, err
+		}
+		replicaDBs = append(replicaDBs, replicaDB)
+	}
+	return NewCluster(db, replicaDBs...), nil
+}
+
+func NewCluster(primary *DB, replicas ...*DB) *DB {
+	c := &cluster{
+		replicas: make([]*replica, len(replicas)),
+		downtime: defaultReplicaDowntime,
+	}
+	for i, r := range replicas {
+		c.replicas[i] = &replica{DB: r}
+	}
+	primary.cluster = c
+	return primary
+}
+
+func (d *DB) ReplicaPolicy(policy ReplicaPolicy) {
+	d.replicaPolicy = policy
+}
+
+func (d *DB) Close() error {
+	err := d.DB.Close()
+	if d.cluster != nil {
+		for _, r := range d.cluster.replicas {
+			err = errors.Join(err, r.DB.Close())
+		}
+	}
+	return err
+}
+
+func (q *Quirk) Primary() *Quirk {
+	q.primary = true
+	return q
+}
+
+func (d *DB) selectReplica(query string, primary bool) *replica {
+	if d.cluster == nil || primary || d.txDepth > 0 || d.conn != nil || !isReplicaQuery(query) {
+		return nil
+	}
+	now := time.Now().UnixNano()
+	healthy := make([]*replica, 0, len(d.cluster.replicas))
+	for _, r := range d.cluster.replicas {
+		if r.downUntil.Load() <= now {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	if d.replicaPolicy == LeastConnections {
+		selected := healthy[0]
+		for _, r := range healthy[1:] {
+			if r.Stats().InUse < selected.Stats().InUse {
+				selected = r
+			}
+		}
+		return selected
+	}
+	return healthy[(d.cluster.next.Add(1)-1)%uint64(len(healthy))]
+}
+
+func (r *replica) markDown(downtime time.Duration) {
+	r.downUntil.Store(time.Now().Add(downtime).UnixNano())
+}
+
+func isReplicaQuery(query string) bool {
+	if getOperation(query) != "SELECT" {
+		return false
+	}
+	lower := strings.ToLower(formatSql(query))
+	for _, clause := range lockingClauses {
+		if strings.Contains(lower, clause) {
+			return false
+		}
+	}
+	return true
+}
+
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.As(err, &netErr)
+}
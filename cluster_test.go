@@ -0,0 +1,100 @@
+package quirk
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCluster(t *testing.T) {
+	createMock := func(t *testing.T) (*DB, sqlmock.Sqlmock) {
+		sqlDB, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+		return wrapConnection(sqlDB, Postgres), mock
+	}
+	t.Run(
+		"round robin reads and primary writes", func(t *testing.T) {
+			primary, primaryMock := createMock(t)
+			replicaA, replicaAMock := createMock(t)
+			replicaB, replicaBMock := createMock(t)
+			db := NewCluster(primary, replicaA, replicaB)
+			replicaAMock.ExpectQuery(`SELECT 1;`).WillReturnRows(sqlmock.NewRows(nil))
+			replicaBMock.ExpectQuery(`SELECT 2;`).WillReturnRows(sqlmock.NewRows(nil))
+			replicaAMock.ExpectQuery(`SELECT 3;`).WillReturnRows(sqlmock.NewRows(nil))
+			primaryMock.ExpectQuery(`SELECT \* FROM tests FOR UPDATE;`).WillReturnRows(sqlmock.NewRows(nil))
+			primaryMock.ExpectQuery(`UPDATE tests SET active = false;`).WillReturnRows(sqlmock.NewRows(nil))
+			primaryMock.ExpectQuery(`SELECT 4;`).WillReturnRows(sqlmock.NewRows(nil))
+			assert.Nil(t, db.Q(`SELECT 1`).Exec())
+			assert.Nil(t, db.Q(`SELECT 2`).Exec())
+			assert.Nil(t, db.Q(`SELECT 3`).Exec())
+			assert.Nil(t, db.Q(`SELECT * FROM tests FOR UPDATE`).Exec())
+			assert.Nil(t, db.Q(`UPDATE tests SET active = false`).Exec())
+			assert.Nil(t, db.Q(`SELECT 4`).Primary().Exec())
+			assert.Nil(t, primaryMock.ExpectationsWereMet())
+			assert.Nil(t, replicaAMock.ExpectationsWereMet())
+			assert.Nil(t, replicaBMock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"transaction stays on primary", func(t *testing.T) {
+			primary, primaryMock := createMock(t)
+			replica, replicaMock := createMock(t)
+			db := NewCluster(primary, replica)
+			primaryMock.ExpectQuery(`BEGIN;`).WillReturnRows(sqlmock.NewRows(nil))
+			primaryMock.ExpectQuery(`SELECT 1;`).WillReturnRows(sqlmock.NewRows(nil))
+			tx := db.MustBegin()
+			assert.Nil(t, tx.Q(`SELECT 1`).Exec())
+			assert.Nil(t, primaryMock.ExpectationsWereMet())
+			assert.Nil(t, replicaMock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"reads after transaction use replica", func(t *testing.T) {
+			primary, primaryMock := createMock(t)
+			replica, replicaMock := createMock(t)
+			db := NewCluster(primary, replica)
+			primaryMock.ExpectQuery(`BEGIN;`).WillReturnRows(sqlmock.NewRows(nil))
+			primaryMock.ExpectQuery(`COMMIT;`).WillReturnRows(sqlmock.NewRows(nil))
+			replicaMock.ExpectQuery(`SELECT 1;`).WillReturnRows(sqlmock.NewRows(nil))
+			tx := db.MustBegin()
+			tx.MustCommit()
+			assert.Nil(t, db.Q(`SELECT 1`).Exec())
+			assert.Nil(t, primaryMock.ExpectationsWereMet())
+			assert.Nil(t, replicaMock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"failover to primary", func(t *testing.T) {
+			primary, primaryMock := createMock(t)
+			replica, replicaMock := createMock(t)
+			db := NewCluster(primary, replica)
+			replicaMock.ExpectQuery(`SELECT 1;`).WillReturnError(&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")})
+			primaryMock.ExpectQuery(`SELECT 1;`).WillReturnRows(sqlmock.NewRows(nil))
+			primaryMock.ExpectQuery(`SELECT 2;`).WillReturnRows(sqlmock.NewRows(nil))
+			assert.Nil(t, db.Q(`SELECT 1`).Exec())
+			assert.Nil(t, db.Q(`SELECT 2`).Exec())
+			assert.Nil(t, primaryMock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"least connections", func(t *testing.T) {
+			primary, _ := createMock(t)
+			replicaA, _ := createMock(t)
+			replicaB, replicaBMock := createMock(t)
+			db := NewCluster(primary, replicaA, replicaB)
+			db.ReplicaPolicy(LeastConnections)
+			replicaBMock.ExpectQuery(`SELECT 1;`).WillReturnRows(sqlmock.NewRows(nil))
+			conn, err := replicaA.Conn(context.Background())
+			assert.Nil(t, err)
+			defer func() {
+				_ = conn.Close()
+			}()
+			assert.Nil(t, db.Q(`SELECT 1`).Exec())
+			assert.Nil(t, replicaBMock.ExpectationsWereMet())
+		},
+	)
+}
@@ -0,0 +1,89 @@
+package quirk
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type commentTagsKey struct{}
+
+const (
+	CommentTagCaller      = "caller"
+	CommentTagFile        = "file"
+	CommentTagRoute       = "route"
+	CommentTagTraceparent = "traceparent"
+)
+
+var (
+	commentValueEscaper = strings.NewReplacer("+", "%20", "'", `\'`)
+	traceparentMatcher  = regexp.MustCompile(`^[0-9a-f]{2}-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2}$`)
+)
+
+func (d *DB) Comment(use ...bool) {
+	c := true
+	if len(use) > 0 {
+		c = use[0]
+	}
+	d.comment = c
+}
+
+func WithRoute(ctx context.Context, route string) context.Context {
+	return WithCommentTags(ctx, map[string]string{CommentTagRoute: route})
+}
+
+// WithTraceparent expects a W3C traceparent header value (version-traceid-spanid-flags),
+// anything else is not a valid sqlcommenter traceparent and is ignored.
+func WithTraceparent(ctx context.Context, traceparent string) context.Context {
+	if !traceparentMatcher.MatchString(traceparent) {
+		return ctx
+	}
+	return WithCommentTags(ctx, map[string]string{CommentTagTraceparent: traceparent})
+}
+
+func WithCommentTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string)
+	if existing, ok := ctx.Value(commentTagsKey{}).(map[string]string); ok {
+		maps.Copy(merged, existing)
+	}
+	maps.Copy(merged, tags)
+	return context.WithValue(ctx, commentTagsKey{}, merged)
+}
+
+func createComment(ctx context.Context) string {
+	tags := make(map[string]string)
+	if frame, ok := getCallerFrame(); ok {
+		tags[CommentTagCaller] = frame.Function
+		tags[CommentTagFile] = fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+	}
+	if ctx != nil {
+		if existing, ok := ctx.Value(commentTagsKey{}).(map[string]string); ok {
+			maps.Copy(tags, existing)
+		}
+	}
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if len(tags[key]) == 0 {
+			continue
+		}
+		result = append(result, fmt.Sprintf("%s='%s'", escapeCommentValue(key), escapeCommentValue(tags[key])))
+	}
+	if len(result) == 0 {
+		return ""
+	}
+	return "/*" + strings.Join(result, ",") + "*/"
+}
+
+func escapeCommentValue(value string) string {
+	return commentValueEscaper.Replace(url.QueryEscape(value))
+}
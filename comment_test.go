@@ -0,0 +1,80 @@
+package quirk
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComment(t *testing.T) {
+	t.Run(
+		"escape tags", func(t *testing.T) {
+			ctx := WithRoute(context.Background(), "/users/{id}")
+			ctx = WithTraceparent(ctx, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			ctx = WithCommentTags(ctx, map[string]string{"tenant": "it's */ me"})
+			comment := createComment(ctx)
+			assert.Contains(t, comment, `route='%2Fusers%2F%7Bid%7D'`)
+			assert.Contains(t, comment, `traceparent='00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'`)
+			assert.Contains(t, comment, `tenant='it%27s%20%2A%2F%20me'`)
+			assert.Regexp(t, `file='[^']+%3A[0-9]+'`, comment)
+			assert.Equal(t, 1, strings.Count(comment, "*/"))
+		},
+	)
+	t.Run(
+		"invalid traceparent", func(t *testing.T) {
+			ctx := WithTraceparent(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736")
+			assert.NotContains(t, createComment(ctx), CommentTagTraceparent)
+		},
+	)
+	t.Run(
+		"prepend to statement", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			db.Comment()
+			var event QueryEvent
+			db.Subscribe(
+				func(e QueryEvent) {
+					event = e
+				},
+			)
+			q := db.Q(`SELECT 1`).Context(WithRoute(context.Background(), "/health"))
+			mock.ExpectQuery(`^/\*caller='.+',file='[^']+%3A[0-9]+',route='%2Fhealth'\*/ SELECT 1;$`).
+				WillReturnRows(sqlmock.NewRows(nil))
+			assert.Nil(t, q.Exec())
+			assert.Nil(t, mock.ExpectationsWereMet())
+			fingerprint, _ := Fingerprint(event.Query)
+			assert.Equal(t, "select ?", fingerprint)
+		},
+	)
+	t.Run(
+		"read-only middleware and span operation", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			db.Comment()
+			db.Use(ReadOnlyMiddleware())
+			tracer := NewRecordingTracer()
+			db.Tracer(tracer)
+			mock.ExpectQuery(`^/\*caller='.+'\*/ SELECT 1;$`).WillReturnRows(sqlmock.NewRows(nil))
+			assert.Nil(t, db.Q(`SELECT 1`).Exec())
+			assert.ErrorIs(t, db.Q(`DELETE FROM tests`).Exec(), ErrorReadOnly)
+			assert.Nil(t, mock.ExpectationsWereMet())
+			spans := tracer.Spans()
+			assert.Len(t, spans, 2)
+			assert.Equal(t, "SELECT", spans[0].Name)
+			assert.Equal(t, "SELECT", spans[0].Attributes[AttributeDbOperation])
+			assert.Equal(t, "DELETE", spans[1].Name)
+		},
+	)
+	t.Run(
+		"operation after comments", func(t *testing.T) {
+			assert.Equal(t, "SELECT", getOperation("/*caller='x'*/ SELECT 1"))
+			assert.Equal(t, "UPDATE", getOperation("-- note\n/* a */ (UPDATE tests SET id = 1)"))
+			assert.Equal(t, "", getOperation("/* unterminated"))
+		},
+	)
+}
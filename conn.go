@@ -0,0 +1,125 @@
+package quirk
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync/atomic"
+)
+
+type conn struct {
+	driver.Conn
+	bad     atomic.Bool
+	isFatal func(err error) bool
+}
+
+func wrapDriverConn(c driver.Conn, isFatal func(err error) bool) *conn {
+	return &conn{Conn: c, isFatal: isFatal}
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err := b.BeginTx(ctx, opts)
+		return tx, c.check(err)
+	}
+	tx, err := c.Conn.Begin()
+	return tx, c.check(err)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err := p.PrepareContext(ctx, query)
+		return stmt, c.check(err)
+	}
+	stmt, err := c.Conn.Prepare(query)
+	return stmt, c.check(err)
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	rows, err := q.QueryContext(ctx, query, args)
+	return rows, c.check(err)
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	result, err := e.ExecContext(ctx, query, args)
+	return result, c.check(err)
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return c.check(p.Ping(ctx))
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if c.bad.Load() {
+		return driver.ErrBadConn
+	}
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if c.bad.Load() {
+		return false
+	}
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) check(err error) error {
+	if err != nil && c.isFatal != nil && c.isFatal(err) {
+		c.bad.Store(true)
+	}
+	return err
+}
+
+func execDriverConn(ctx context.Context, c driver.Conn, query string) error {
+	if e, ok := c.(driver.ExecerContext); ok {
+		_, err := e.ExecContext(ctx, query, nil)
+		return err
+	}
+	stmt, err := c.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+	_, err = stmt.Exec(nil)
+	return err
+}
+
+func queryDriverConnValue(ctx context.Context, c driver.Conn, query string) (driver.Value, error) {
+	q, ok := c.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	rows, err := q.QueryContext(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	dest := make([]driver.Value, len(rows.Columns()))
+	if err := rows.Next(dest); err != nil {
+		return nil, err
+	}
+	if len(dest) == 0 {
+		return nil, nil
+	}
+	return dest[0], nil
+}
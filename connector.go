@@ -1,11 +1,17 @@
 package quirk
 
 import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
-
-	_ "github.com/lib/pq"
+	"time"
 )
 
 type Config interface{}
@@ -25,6 +31,40 @@ const (
 	configTypeSsl
 	configTypeCertPath
 	configTypeLog
+	configTypeLogger
+	configTypeSlowQueryThreshold
+	configTypeTracer
+	configTypeRedact
+	configTypeComment
+	configTypeStatementTimeout
+	configTypeLockTimeout
+	configTypeMaxOpenConns
+	configTypeMaxIdleConns
+	configTypeConnMaxLifetime
+	configTypeConnMaxIdleTime
+	configTypePingOnConnect
+	configTypeUrl
+	configTypeEnv
+	configTypeRootCert
+	configTypeClientCert
+	configTypeRootCertPem
+	configTypeClientCertPem
+	configTypeTlsConfig
+	configTypeApplicationName
+	configTypeConnectTimeout
+	configTypeSearchPath
+	configTypeOptions
+	configTypeParam
+	configTypeGroup
+	configTypeReplicaPolicy
+	configTypeHosts
+	configTypeHostOrder
+	configTypeTargetSessionAttrs
+	configTypeConnectRetry
+	configTypeTopologyHook
+	configTypeOnConnect
+	configTypeInitSql
+	configTypePasswordFunc
 )
 
 const (
@@ -41,9 +81,18 @@ func Connect(configs ...Config) (*DB, error) {
 	if err != nil {
 		return nil, err
 	}
-	db, err := Open(driver, dataSource)
-	db.log = log
-	return db, err
+	db, err := open(driver, dataSource, configs...)
+	if err != nil {
+		return nil, err
+	}
+	if log {
+		db.Log()
+	}
+	if err := configureDB(db, configs...); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
 }
 
 func MustConnect(configs ...Config) *DB {
@@ -54,10 +103,107 @@ func MustConnect(configs ...Config) *DB {
 	return db
 }
 
+func open(driverName, dataSource string, configs ...Config) (*DB, error) {
+	configs = flattenConfigs(configs...)
+	fc := &failoverConnector{dataSource: dataSource, target: TargetSessionAny}
+	var urlTarget string
+	var explicitTarget bool
+	var tlsConfig *tls.Config
+	hooks := make([]ConnectHook, 0)
+	var password PasswordFunc
+	for _, item := range configs {
+		c, ok := item.(config)
+		if !ok {
+			continue
+		}
+		switch c.configType {
+		case configTypeTlsConfig:
+			tlsConfig, _ = c.value.(*tls.Config)
+		case configTypeHosts:
+			fc.hosts, _ = c.value.([]string)
+		case configTypeHostOrder:
+			fc.order, _ = c.value.(HostOrder)
+		case configTypeUrl:
+			if target := parseUrlTargetSession(fmt.Sprintf("%v", c.value)); len(target) > 0 {
+				urlTarget = target
+			}
+		case configTypeTargetSessionAttrs:
+			fc.target = fmt.Sprintf("%v", c.value)
+			explicitTarget = true
+		case configTypeTopologyHook:
+			fc.onChange, _ = c.value.(func(TopologyChange))
+		case configTypeOnConnect:
+			if hook, ok := c.value.(ConnectHook); ok && hook != nil {
+				hooks = append(hooks, hook)
+			}
+		case configTypePasswordFunc:
+			password, _ = c.value.(PasswordFunc)
+		case configTypeInitSql:
+			if statements, ok := c.value.([]string); ok && len(statements) > 0 {
+				hooks = append(hooks, createInitSqlHook(statements...))
+			}
+		}
+	}
+	if len(urlTarget) > 0 && !explicitTarget {
+		fc.target = urlTarget
+	}
+	if !slices.Contains(targetSessionAttrs, fc.target) {
+		return nil, fmt.Errorf("%w: invalid target_session_attrs %q", ErrorInvalidConfig, fc.target)
+	}
+	if fc.target != TargetSessionAny && driverName != Postgres {
+		return nil, fmt.Errorf("%w: target_session_attrs requires %s driver", ErrorInvalidConfig, Postgres)
+	}
+	if password != nil && driverName != Postgres {
+		return nil, fmt.Errorf("%w: password provider requires %s driver", ErrorInvalidConfig, Postgres)
+	}
+	if tlsConfig != nil {
+		fc.dialer = &tlsDialer{config: tlsConfig}
+	}
+	fc.password = password
+	failover := driverName == Postgres && (len(fc.hosts) > 0 || fc.target != TargetSessionAny)
+	var connector driver.Connector
+	switch {
+	case failover:
+		connector = fc
+	case driverName == Postgres && (tlsConfig != nil || password != nil):
+		connector = &pgConnector{dataSource: dataSource, dialer: fc.dialer, password: password}
+	case len(hooks) > 0:
+		c, err := openConnector(driverName, dataSource)
+		if err != nil {
+			return nil, err
+		}
+		connector = c
+	}
+	var db *DB
+	if connector == nil {
+		var err error
+		if db, err = Open(driverName, dataSource); err != nil {
+			return nil, err
+		}
+	} else {
+		if len(hooks) > 0 {
+			connector = &hookConnector{Connector: connector, hooks: hooks}
+		}
+		db = wrapConnection(sql.OpenDB(connector), driverName)
+	}
+	db.dialer = fc.dialer
+	db.dataSource = func(ctx context.Context) (string, error) {
+		if failover {
+			return fc.currentDataSource(ctx)
+		}
+		return resolvePassword(ctx, dataSource, password)
+	}
+	return db, nil
+}
+
 func createConnectionDataSource(configs ...Config) (string, string, bool, error) {
-	var driver string
 	var log bool
-	props := make([]string, 0)
+	var inlineCerts, fileCerts, customTls bool
+	configs = flattenConfigs(configs...)
+	driver := findDriver(configs...)
+	props := newDataSource()
+	urlParams := make([]dataSourceParam, 0)
+	environmentParams := make([]dataSourceParam, 0)
 	for _, item := range configs {
 		c, ok := item.(config)
 		if !ok {
@@ -69,36 +215,235 @@ func createConnectionDataSource(configs ...Config) (string, string, bool, error)
 			case bool:
 				log = v
 			}
-		case configTypeDriver:
-			driver = fmt.Sprintf("%v", c.value)
+		case configTypeUrl:
+			params, err := parseConnectionUrl(fmt.Sprintf("%v", c.value))
+			if err != nil {
+				return "", "", false, err
+			}
+			urlParams = append(urlParams, params...)
+		case configTypeEnv:
+			environmentParams = append(environmentParams, readEnvParams(fmt.Sprintf("%v", c.value))...)
 		case configTypeHost:
-			props = append(props, fmt.Sprintf("host=%v", c.value))
+			props.set(paramHost, fmt.Sprintf("%v", c.value))
 		case configTypePort:
-			props = append(props, fmt.Sprintf("port=%v", c.value))
+			props.set(paramPort, fmt.Sprintf("%v", c.value))
 		case configTypeDbname:
-			props = append(props, fmt.Sprintf("dbname=%v", c.value))
+			props.set(paramDbname, fmt.Sprintf("%v", c.value))
 		case configTypeUser:
-			props = append(props, fmt.Sprintf("user=%v", c.value))
+			props.set(paramUser, fmt.Sprintf("%v", c.value))
 		case configTypePassword:
-			props = append(props, fmt.Sprintf("password=%v", c.value))
+			props.set(paramPassword, fmt.Sprintf("%v", c.value))
 		case configTypeSsl:
-			props = append(props, fmt.Sprintf("sslmode=%v", c.value))
+			props.set(paramSslmode, fmt.Sprintf("%v", c.value))
+		case configTypeStatementTimeout:
+			if timeout, ok := c.value.(time.Duration); ok && driver == Postgres {
+				props.set(statementTimeoutParam, formatTimeoutParam(timeout))
+			}
+		case configTypeLockTimeout:
+			if timeout, ok := c.value.(time.Duration); ok && driver == Postgres {
+				props.set(lockTimeoutParam, formatTimeoutParam(timeout))
+			}
 		case configTypeCertPath:
 			v := fmt.Sprintf("%v", c.value)
-			dir, err := os.Getwd()
-			if err != nil {
-				return "", "", false, err
+			if !filepath.IsAbs(v) {
+				dir, err := os.Getwd()
+				if err != nil {
+					return "", "", false, err
+				}
+				v = filepath.Join(dir, v)
+			}
+			fileCerts = true
+			props.set(paramSslrootcert, v)
+		case configTypeRootCert:
+			fileCerts = true
+			props.set(paramSslrootcert, fmt.Sprintf("%v", c.value))
+		case configTypeClientCert:
+			if v, ok := c.value.([2]string); ok {
+				fileCerts = true
+				props.set(paramSslcert, v[0])
+				props.set(paramSslkey, v[1])
+			}
+		case configTypeRootCertPem:
+			if v, ok := c.value.([]byte); ok {
+				inlineCerts = true
+				props.set(paramSslrootcert, string(v))
+			}
+		case configTypeClientCertPem:
+			if v, ok := c.value.([2][]byte); ok {
+				inlineCerts = true
+				props.set(paramSslcert, string(v[0]))
+				props.set(paramSslkey, string(v[1]))
+			}
+		case configTypeTlsConfig:
+			v, _ := c.value.(*tls.Config)
+			customTls = v != nil
+		case configTypeApplicationName:
+			props.set(paramApplicationName, fmt.Sprintf("%v", c.value))
+		case configTypeConnectTimeout:
+			if timeout, ok := c.value.(time.Duration); ok {
+				props.set(paramConnectTimeout, formatConnectTimeout(timeout))
+			}
+		case configTypeSearchPath:
+			if schemas, ok := c.value.([]string); ok {
+				props.set(paramSearchPath, strings.Join(schemas, ","))
+			}
+		case configTypeOptions:
+			if options, ok := c.value.([]string); ok {
+				props.set(paramOptions, strings.Join(options, " "))
+			}
+		case configTypeParam:
+			if p, ok := c.value.(dataSourceParam); ok {
+				if err := validateServerParam(p.key); err != nil {
+					return "", "", false, err
+				}
+				props.set(p.key, p.value)
+			}
+		}
+	}
+	for _, p := range append(urlParams, environmentParams...) {
+		props.setDefault(p.key, p.value)
+	}
+	if inlineCerts && fileCerts {
+		return "", "", false, fmt.Errorf("%w: inline PEM and certificate files cannot be combined", ErrorInvalidCertificate)
+	}
+	if inlineCerts {
+		props.set(paramSslinline, "true")
+	}
+	if customTls {
+		if mode, ok := props.get(paramSslmode); ok && mode != sslDisable {
+			return "", "", false, fmt.Errorf(
+				"%w: sslmode %q conflicts with custom TLS config, which negotiates TLS itself", ErrorInvalidConfig, mode,
+			)
+		}
+		props.set(paramSslmode, sslDisable)
+	}
+	if err := props.validate(); err != nil {
+		return "", "", false, err
+	}
+	if !customTls {
+		if err := validateCertFiles(props); err != nil {
+			return "", "", false, err
+		}
+	}
+	return driver, props.String(), log, nil
+}
+
+func findDriver(configs ...Config) string {
+	var driver string
+	for _, item := range configs {
+		c, ok := item.(config)
+		if !ok {
+			continue
+		}
+		switch c.configType {
+		case configTypeDriver:
+			driver = fmt.Sprintf("%v", c.value)
+		case configTypeUrl, configTypeEnv:
+			if len(driver) == 0 {
+				driver = Postgres
+			}
+		}
+	}
+	return driver
+}
+
+func flattenConfigs(configs ...Config) []Config {
+	result := make([]Config, 0, len(configs))
+	for _, item := range configs {
+		c, ok := item.(config)
+		if !ok || c.configType != configTypeGroup {
+			result = append(result, item)
+			continue
+		}
+		if group, ok := c.value.([]Config); ok {
+			result = append(result, flattenConfigs(group...)...)
+		}
+	}
+	return result
+}
+
+func configureDB(db *DB, configs ...Config) error {
+	var pingTimeout time.Duration
+	var retry *connectRetry
+	configs = flattenConfigs(configs...)
+	for _, item := range configs {
+		c, ok := item.(config)
+		if !ok {
+			continue
+		}
+		switch c.configType {
+		case configTypeLogger:
+			if logger, ok := c.value.(*slog.Logger); ok {
+				db.Logger(logger)
+			}
+		case configTypeSlowQueryThreshold:
+			if threshold, ok := c.value.(time.Duration); ok {
+				db.SlowQueryThreshold(threshold)
+			}
+		case configTypeTracer:
+			if tracer, ok := c.value.(Tracer); ok {
+				db.Tracer(tracer)
 			}
-			if strings.HasSuffix(dir, "/") {
-				dir = strings.TrimSuffix(dir, "/")
+		case configTypeRedact:
+			if names, ok := c.value.([]string); ok {
+				db.Redact(names...)
 			}
-			if !strings.HasPrefix(v, "/") {
-				v = "/" + v
+		case configTypeComment:
+			if comment, ok := c.value.(bool); ok {
+				db.Comment(comment)
+			}
+		case configTypeStatementTimeout:
+			if timeout, ok := c.value.(time.Duration); ok {
+				db.StatementTimeout(timeout)
+			}
+		case configTypeLockTimeout:
+			if timeout, ok := c.value.(time.Duration); ok {
+				db.LockTimeout(timeout)
+			}
+		case configTypeMaxOpenConns:
+			if n, ok := c.value.(int); ok {
+				db.SetMaxOpenConns(n)
+			}
+		case configTypeMaxIdleConns:
+			if n, ok := c.value.(int); ok {
+				db.SetMaxIdleConns(n)
+			}
+		case configTypeConnMaxLifetime:
+			if d, ok := c.value.(time.Duration); ok {
+				db.SetConnMaxLifetime(d)
+			}
+		case configTypeConnMaxIdleTime:
+			if d, ok := c.value.(time.Duration); ok {
+				db.SetConnMaxIdleTime(d)
+			}
+		case configTypePingOnConnect:
+			if d, ok := c.value.(time.Duration); ok {
+				pingTimeout = d
+			}
+		case configTypeConnectRetry:
+			if r, ok := c.value.(connectRetry); ok {
+				retry = &r
+			}
+		case configTypeReplicaPolicy:
+			if policy, ok := c.value.(ReplicaPolicy); ok {
+				db.ReplicaPolicy(policy)
 			}
-			props = append(props, fmt.Sprintf("sslrootcert=%s", dir+v))
 		}
 	}
-	return driver, strings.Join(props, " "), log, nil
+	if retry != nil {
+		return db.pingWithRetry(pingTimeout, *retry)
+	}
+	if pingTimeout > 0 {
+		return db.ping(pingTimeout)
+	}
+	return nil
+}
+
+func Configs(configs ...Config) Config {
+	return config{
+		configType: configTypeGroup,
+		value:      configs,
+	}
 }
 
 func WithLog(log bool) Config {
@@ -108,6 +453,104 @@ func WithLog(log bool) Config {
 	}
 }
 
+func WithLogger(logger *slog.Logger) Config {
+	return config{
+		configType: configTypeLogger,
+		value:      logger,
+	}
+}
+
+func WithSlowQueryThreshold(threshold time.Duration) Config {
+	return config{
+		configType: configTypeSlowQueryThreshold,
+		value:      threshold,
+	}
+}
+
+func WithTracer(tracer Tracer) Config {
+	return config{
+		configType: configTypeTracer,
+		value:      tracer,
+	}
+}
+
+func WithRedact(names ...string) Config {
+	return config{
+		configType: configTypeRedact,
+		value:      names,
+	}
+}
+
+func WithSqlComment(comment bool) Config {
+	return config{
+		configType: configTypeComment,
+		value:      comment,
+	}
+}
+
+func WithStatementTimeout(timeout time.Duration) Config {
+	return config{
+		configType: configTypeStatementTimeout,
+		value:      timeout,
+	}
+}
+
+func WithLockTimeout(timeout time.Duration) Config {
+	return config{
+		configType: configTypeLockTimeout,
+		value:      timeout,
+	}
+}
+
+func WithMaxOpenConns(n int) Config {
+	return config{
+		configType: configTypeMaxOpenConns,
+		value:      n,
+	}
+}
+
+func WithMaxIdleConns(n int) Config {
+	return config{
+		configType: configTypeMaxIdleConns,
+		value:      n,
+	}
+}
+
+func WithConnMaxLifetime(d time.Duration) Config {
+	return config{
+		configType: configTypeConnMaxLifetime,
+		value:      d,
+	}
+}
+
+func WithConnMaxIdleTime(d time.Duration) Config {
+	return config{
+		configType: configTypeConnMaxIdleTime,
+		value:      d,
+	}
+}
+
+func WithPingOnConnect(timeout time.Duration) Config {
+	return config{
+		configType: configTypePingOnConnect,
+		value:      timeout,
+	}
+}
+
+func WithURL(url string) Config {
+	return config{
+		configType: configTypeUrl,
+		value:      url,
+	}
+}
+
+func WithEnv(prefix string) Config {
+	return config{
+		configType: configTypeEnv,
+		value:      prefix,
+	}
+}
+
 func WithPostgres() Config {
 	return config{
 		configType: configTypeDriver,
@@ -213,9 +656,152 @@ func WithSslVerifyFull() Config {
 	}
 }
 
+// Deprecated: use WithRootCert, relative paths are resolved against the working directory
 func WithCertPath(certpath string) Config {
 	return config{
 		configType: configTypeCertPath,
 		value:      certpath,
 	}
 }
+
+func WithRootCert(path string) Config {
+	return config{
+		configType: configTypeRootCert,
+		value:      path,
+	}
+}
+
+func WithClientCert(cert, key string) Config {
+	return config{
+		configType: configTypeClientCert,
+		value:      [2]string{cert, key},
+	}
+}
+
+func WithRootCertPEM(pem []byte) Config {
+	return config{
+		configType: configTypeRootCertPem,
+		value:      pem,
+	}
+}
+
+func WithClientCertPEM(cert, key []byte) Config {
+	return config{
+		configType: configTypeClientCertPem,
+		value:      [2][]byte{cert, key},
+	}
+}
+
+// WithTLSConfig negotiates TLS in a custom dialer, so the driver itself runs with sslmode=disable.
+// Combining it with any other sslmode is rejected.
+func WithTLSConfig(tlsConfig *tls.Config) Config {
+	return config{
+		configType: configTypeTlsConfig,
+		value:      tlsConfig,
+	}
+}
+
+func WithApplicationName(name string) Config {
+	return config{
+		configType: configTypeApplicationName,
+		value:      name,
+	}
+}
+
+func WithConnectTimeout(timeout time.Duration) Config {
+	return config{
+		configType: configTypeConnectTimeout,
+		value:      timeout,
+	}
+}
+
+func WithSearchPath(schemas ...string) Config {
+	return config{
+		configType: configTypeSearchPath,
+		value:      schemas,
+	}
+}
+
+func WithOptions(options ...string) Config {
+	return config{
+		configType: configTypeOptions,
+		value:      options,
+	}
+}
+
+func WithParam(key, value string) Config {
+	return config{
+		configType: configTypeParam,
+		value:      dataSourceParam{key, value},
+	}
+}
+
+func WithReplicaPolicy(policy ReplicaPolicy) Config {
+	return config{
+		configType: configTypeReplicaPolicy,
+		value:      policy,
+	}
+}
+
+func WithHosts(hosts ...string) Config {
+	return config{
+		configType: configTypeHosts,
+		value:      hosts,
+	}
+}
+
+func WithHostOrder(order HostOrder) Config {
+	return config{
+		configType: configTypeHostOrder,
+		value:      order,
+	}
+}
+
+func WithTargetSessionAttrs(target string) Config {
+	return config{
+		configType: configTypeTargetSessionAttrs,
+		value:      target,
+	}
+}
+
+func WithConnectRetry(attempts int, backoff, maxBackoff time.Duration) Config {
+	return config{
+		configType: configTypeConnectRetry,
+		value:      connectRetry{attempts: attempts, backoff: backoff, max: maxBackoff},
+	}
+}
+
+func WithTopologyHook(hook func(change TopologyChange)) Config {
+	return config{
+		configType: configTypeTopologyHook,
+		value:      hook,
+	}
+}
+
+func WithOnConnect(hook func(ctx context.Context, conn driver.Conn) error) Config {
+	return config{
+		configType: configTypeOnConnect,
+		value:      ConnectHook(hook),
+	}
+}
+
+func WithInitSQL(statements ...string) Config {
+	return config{
+		configType: configTypeInitSql,
+		value:      statements,
+	}
+}
+
+func WithPasswordFunc(password func(ctx context.Context) (string, error)) Config {
+	return config{
+		configType: configTypePasswordFunc,
+		value:      PasswordFunc(password),
+	}
+}
+
+func WithPasswordFile(path string) Config {
+	return config{
+		configType: configTypePasswordFunc,
+		value:      readPasswordFile(path),
+	}
+}
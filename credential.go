@@ -0,0 +1,61 @@
+package quirk
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"strings"
+
+	pg "github.com/lib/pq"
+)
+
+type PasswordFunc func(ctx context.Context) (string, error)
+
+type pgConnector struct {
+	dataSource string
+	dialer     pg.Dialer
+	password   PasswordFunc
+}
+
+func (c *pgConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dataSource, err := resolvePassword(ctx, c.dataSource, c.password)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := pg.NewConnector(dataSource)
+	if err != nil {
+		return nil, err
+	}
+	if c.dialer != nil {
+		pc.Dialer(c.dialer)
+	}
+	return pc.Connect(ctx)
+}
+
+func (c *pgConnector) Driver() driver.Driver {
+	return &pg.Driver{}
+}
+
+func resolvePassword(ctx context.Context, dataSource string, password PasswordFunc) (string, error) {
+	if password == nil {
+		return dataSource, nil
+	}
+	p, err := password(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrorPasswordProvider, err)
+	}
+	props := newDataSource()
+	props.set(paramPassword, p)
+	return strings.TrimSpace(dataSource + " " + props.String()), nil
+}
+
+func readPasswordFile(path string) PasswordFunc {
+	return func(context.Context) (string, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+}
@@ -0,0 +1,111 @@
+package quirk
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentials(t *testing.T) {
+	t.Run(
+		"password per connection", func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			assert.Nil(t, err)
+			defer func() {
+				_ = listener.Close()
+			}()
+			passwords := make(chan string, 2)
+			go acceptPasswordAuth(listener, passwords)
+			host, port, _ := net.SplitHostPort(listener.Addr().String())
+			secrets := []string{"first", "rotated"}
+			calls := 0
+			c := &pgConnector{
+				dataSource: fmt.Sprintf("host=%s port=%s user=app sslmode=disable connect_timeout=1", host, port),
+				password: func(ctx context.Context) (string, error) {
+					calls++
+					return secrets[calls-1], nil
+				},
+			}
+			_, err = c.Connect(context.Background())
+			assert.NotNil(t, err)
+			assert.Equal(t, "first", <-passwords)
+			_, err = c.Connect(context.Background())
+			assert.NotNil(t, err)
+			assert.Equal(t, "rotated", <-passwords)
+			assert.Equal(t, 2, calls)
+		},
+	)
+	t.Run(
+		"password provider failure", func(t *testing.T) {
+			failure := errors.New("vault unavailable")
+			_, err := resolvePassword(
+				context.Background(), "host=localhost", func(ctx context.Context) (string, error) {
+					return "", failure
+				},
+			)
+			assert.ErrorIs(t, err, ErrorPasswordProvider)
+			assert.ErrorIs(t, err, failure)
+		},
+	)
+	t.Run(
+		"rotated password file", func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "password")
+			assert.Nil(t, os.WriteFile(path, []byte("first\n"), 0600))
+			password := readPasswordFile(path)
+			dataSource, err := resolvePassword(context.Background(), "host=localhost", password)
+			assert.Nil(t, err)
+			assert.Equal(t, "host=localhost password=first", dataSource)
+			assert.Nil(t, os.WriteFile(path, []byte("it's rotated\n"), 0600))
+			dataSource, err = resolvePassword(context.Background(), "host=localhost", password)
+			assert.Nil(t, err)
+			assert.Equal(t, `host=localhost password='it\'s rotated'`, dataSource)
+		},
+	)
+	t.Run(
+		"password provider requires postgres", func(t *testing.T) {
+			_, err := open(Mysql, "", WithPasswordFile("password"))
+			assert.ErrorIs(t, err, ErrorInvalidConfig)
+		},
+	)
+}
+
+func acceptPasswordAuth(listener net.Listener, passwords chan<- string) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		r := bufio.NewReader(conn)
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(r, header); err != nil {
+			_ = conn.Close()
+			return
+		}
+		if _, err := io.ReadFull(r, make([]byte, binary.BigEndian.Uint32(header)-4)); err != nil {
+			_ = conn.Close()
+			return
+		}
+		_, _ = conn.Write([]byte{'R', 0, 0, 0, 8, 0, 0, 0, 3})
+		message := make([]byte, 5)
+		if _, err := io.ReadFull(r, message); err != nil || message[0] != 'p' {
+			_ = conn.Close()
+			return
+		}
+		body := make([]byte, binary.BigEndian.Uint32(message[1:])-4)
+		if _, err := io.ReadFull(r, body); err != nil {
+			_ = conn.Close()
+			return
+		}
+		passwords <- string(body[:len(body)-1])
+		_ = conn.Close()
+	}
+}
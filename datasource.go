@@ -0,0 +1,209 @@
+package quirk
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type dataSource struct {
+	keys   []string
+	values map[string]string
+}
+
+type dataSourceParam struct {
+	key   string
+	value string
+}
+
+const (
+	paramHost            = "host"
+	paramPort            = "port"
+	paramDbname          = "dbname"
+	paramUser            = "user"
+	paramPassword        = "password"
+	paramSslmode         = "sslmode"
+	paramSslrootcert     = "sslrootcert"
+	paramApplicationName = "application_name"
+	paramConnectTimeout  = "connect_timeout"
+	paramSearchPath      = "search_path"
+	paramOptions         = "options"
+	paramTargetSession   = "target_session_attrs"
+)
+
+const (
+	defaultEnvPrefix = "PG"
+)
+
+var (
+	urlSchemes = []string{"postgres", "postgresql"}
+	sslModes   = []string{sslDisable, sslAllow, sslPrefer, sslRequire, sslVerifyCa, sslVerifyFull}
+	envParams  = []dataSourceParam{
+		{"HOST", paramHost},
+		{"PORT", paramPort},
+		{"DATABASE", paramDbname},
+		{"USER", paramUser},
+		{"PASSWORD", paramPassword},
+		{"SSLMODE", paramSslmode},
+		{"SSLCERT", "sslcert"},
+		{"SSLKEY", "sslkey"},
+		{"SSLROOTCERT", paramSslrootcert},
+		{"APPNAME", paramApplicationName},
+		{"CONNECT_TIMEOUT", paramConnectTimeout},
+		{"OPTIONS", paramOptions},
+	}
+	clientOnlyParams = []string{
+		"hostaddr", "passfile", "service", "load_balance_hosts", "channel_binding", "gssencmode", "require_auth",
+		"requiressl", "sslcompression",
+	}
+	dataSourceValueEscaper = strings.NewReplacer(`\`, `\\`, "'", `\'`)
+)
+
+func newDataSource() *dataSource {
+	return &dataSource{
+		keys:   make([]string, 0),
+		values: make(map[string]string),
+	}
+}
+
+func (s *dataSource) set(key, value string) {
+	if _, ok := s.values[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	s.values[key] = value
+}
+
+func (s *dataSource) setDefault(key, value string) {
+	if _, ok := s.values[key]; ok {
+		return
+	}
+	s.set(key, value)
+}
+
+func (s *dataSource) get(key string) (string, bool) {
+	value, ok := s.values[key]
+	return value, ok
+}
+
+func (s *dataSource) validate() error {
+	if port, ok := s.values[paramPort]; ok {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("%w: invalid port %q", ErrorInvalidConfig, port)
+		}
+	}
+	if timeout, ok := s.values[paramConnectTimeout]; ok {
+		if n, err := strconv.Atoi(timeout); err != nil || n < 0 {
+			return fmt.Errorf("%w: invalid connect_timeout %q", ErrorInvalidConfig, timeout)
+		}
+	}
+	if sslmode, ok := s.values[paramSslmode]; ok && !slices.Contains(sslModes, sslmode) {
+		return fmt.Errorf("%w: invalid sslmode %q", ErrorInvalidConfig, sslmode)
+	}
+	for _, key := range s.keys {
+		if len(key) == 0 || strings.ContainsAny(key, " ='\\") {
+			return fmt.Errorf("%w: invalid parameter name %q", ErrorInvalidConfig, key)
+		}
+	}
+	return nil
+}
+
+func (s *dataSource) String() string {
+	result := make([]string, len(s.keys))
+	for i, key := range s.keys {
+		result[i] = key + "=" + escapeDataSourceValue(s.values[key])
+	}
+	return strings.Join(result, " ")
+}
+
+func escapeDataSourceValue(value string) string {
+	if len(value) > 0 && !strings.ContainsAny(value, " \t\n'\\") {
+		return value
+	}
+	return "'" + dataSourceValueEscaper.Replace(value) + "'"
+}
+
+func parseConnectionUrl(rawUrl string) ([]dataSourceParam, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrorInvalidConfig, err)
+	}
+	if !slices.Contains(urlSchemes, u.Scheme) {
+		return nil, fmt.Errorf("%w: invalid url scheme %q", ErrorInvalidConfig, u.Scheme)
+	}
+	result := make([]dataSourceParam, 0)
+	if host := u.Hostname(); len(host) > 0 {
+		result = append(result, dataSourceParam{paramHost, host})
+	}
+	if port := u.Port(); len(port) > 0 {
+		result = append(result, dataSourceParam{paramPort, port})
+	}
+	if u.User != nil {
+		if user := u.User.Username(); len(user) > 0 {
+			result = append(result, dataSourceParam{paramUser, user})
+		}
+		if password, ok := u.User.Password(); ok {
+			result = append(result, dataSourceParam{paramPassword, password})
+		}
+	}
+	if dbname := strings.TrimPrefix(u.Path, "/"); len(dbname) > 0 {
+		result = append(result, dataSourceParam{paramDbname, dbname})
+	}
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if key == paramTargetSession {
+			continue
+		}
+		if err := validateServerParam(key); err != nil {
+			return nil, err
+		}
+		result = append(result, dataSourceParam{key, query.Get(key)})
+	}
+	return result, nil
+}
+
+func parseUrlTargetSession(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get(paramTargetSession)
+}
+
+func validateServerParam(key string) error {
+	if key == paramTargetSession {
+		return fmt.Errorf("%w: %s is not a server parameter, use WithTargetSessionAttrs", ErrorInvalidConfig, key)
+	}
+	if slices.Contains(clientOnlyParams, key) {
+		return fmt.Errorf("%w: unsupported client parameter %q", ErrorInvalidConfig, key)
+	}
+	return nil
+}
+
+func readEnvParams(prefix string) []dataSourceParam {
+	if len(prefix) == 0 {
+		prefix = defaultEnvPrefix
+	}
+	result := make([]dataSourceParam, 0)
+	for _, p := range envParams {
+		if value, ok := os.LookupEnv(prefix + p.key); ok {
+			result = append(result, dataSourceParam{p.value, value})
+		}
+	}
+	return result
+}
+
+func formatConnectTimeout(timeout time.Duration) string {
+	seconds := int64(math.Ceil(timeout.Seconds()))
+	return strconv.FormatInt(seconds, 10)
+}
@@ -1,16 +1,36 @@
 package quirk
 
 import (
+	"context"
 	"database/sql"
+	"log/slog"
+	"slices"
 	"time"
+
+	pg "github.com/lib/pq"
 )
 
 type DB struct {
 	*sql.DB
-	driverName  string
-	transaction bool
-	rollback    bool
-	log         bool
+	driverName       string
+	transaction      bool
+	rollback         bool
+	txDepth          int
+	logger           *slog.Logger
+	slowQuery        time.Duration
+	subscriptions    []Subscription
+	middlewares      []Middleware
+	tracer           Tracer
+	redacted         []string
+	comment          bool
+	statementTimeout time.Duration
+	lockTimeout      time.Duration
+	localTimeouts    localTimeouts
+	cluster          *cluster
+	replicaPolicy    ReplicaPolicy
+	conn             *sql.Conn
+	dataSource       func(ctx context.Context) (string, error)
+	dialer           pg.Dialer
 }
 
 const (
@@ -29,7 +49,6 @@ func wrapConnection(db *sql.DB, driverName string) *DB {
 		driverName:  driverName,
 		transaction: false,
 		rollback:    false,
-		log:         false,
 	}
 }
 
@@ -41,27 +60,46 @@ func (d *DB) DriverName() string {
 	return d.driverName
 }
 
+func (d *DB) InTransaction() bool {
+	return d.transaction
+}
+
 func (d *DB) Log(use ...bool) {
 	l := true
 	if len(use) > 0 {
 		l = use[0]
 	}
-	d.log = l
+	if !l {
+		d.logger = nil
+		return
+	}
+	if d.logger == nil {
+		d.logger = createDefaultLogger()
+	}
+}
+
+func (d *DB) Logger(logger *slog.Logger) {
+	d.logger = logger
+}
+
+func (d *DB) SlowQueryThreshold(threshold time.Duration) {
+	d.slowQuery = threshold
 }
 
 func (d *DB) Begin() (*DB, error) {
-	db := &DB{
-		DB:          d.DB,
-		driverName:  d.driverName,
-		transaction: true,
-		rollback:    false,
-		log:         d.log,
-	}
+	db := *d
+	db.transaction = true
+	db.rollback = false
+	db.txDepth = d.txDepth + 1
+	db.subscriptions = slices.Clone(d.subscriptions)
+	db.middlewares = slices.Clone(d.middlewares)
 	q := "BEGIN;"
+	_, span := db.startQuerySpan(context.Background(), q)
 	t := time.Now()
-	_, err := d.DB.Query(q)
-	log(db.log, q, time.Now().Sub(t))
-	return db, err
+	err := d.execStatement(context.Background(), q)
+	db.publish(db.createQueryEvent(t, q, nil, 0, err))
+	span.End(err)
+	return &db, err
 }
 
 func (d *DB) Rollback() error {
@@ -70,9 +108,11 @@ func (d *DB) Rollback() error {
 	}
 	d.rollback = true
 	q := "ROLLBACK;"
+	_, span := d.startQuerySpan(context.Background(), q)
 	t := time.Now()
-	_, err := d.DB.Query(q)
-	log(d.log, q, time.Now().Sub(t))
+	err := d.execStatement(context.Background(), q)
+	d.publish(d.createQueryEvent(t, q, nil, 0, err))
+	span.End(err)
 	return err
 }
 
@@ -81,9 +121,11 @@ func (d *DB) Commit() error {
 		return nil
 	}
 	q := "COMMIT;"
+	_, span := d.startQuerySpan(context.Background(), q)
 	t := time.Now()
-	_, err := d.DB.Query(q)
-	log(d.log, q, time.Now().Sub(t))
+	err := d.execStatement(context.Background(), q)
+	d.publish(d.createQueryEvent(t, q, nil, 0, err))
+	span.End(err)
 	return err
 }
 
@@ -3,5 +3,15 @@ package quirk
 import "errors"
 
 var (
-	ErrorMismatchArgs = errors.New("placeholders and args count mismatch")
+	ErrorMismatchArgs        = errors.New("placeholders and args count mismatch")
+	ErrorReadOnly            = errors.New("statement is not allowed in read-only mode")
+	ErrQueryCanceled         = errors.New("query canceled")
+	ErrorInvalidConfig       = errors.New("invalid connection config")
+	ErrorInvalidCertificate  = errors.New("invalid certificate")
+	ErrorSslNotSupported     = errors.New("server does not support SSL")
+	ErrorNoHostAvailable     = errors.New("no suitable host available")
+	ErrorInvalidShard        = errors.New("invalid shard")
+	ErrorInvalidDestination  = errors.New("destination must be a pointer to slice")
+	ErrorPasswordProvider    = errors.New("password provider failed")
+	ErrorUnpinnedTransaction = errors.New("transaction is not pinned to a session connection")
 )
@@ -0,0 +1,44 @@
+package quirk
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	explainableStatements = []string{"select", "insert", "update", "delete", "with", "values", "table"}
+)
+
+func (d *DB) isSlowQuery(duration time.Duration) bool {
+	return d.slowQuery > 0 && duration > d.slowQuery
+}
+
+func (d *DB) explain(target querier, query string, args []any) json.RawMessage {
+	if !isExplainable(query) {
+		return nil
+	}
+	var prefix string
+	switch d.driverName {
+	case Postgres:
+		prefix = "EXPLAIN (FORMAT JSON) "
+	case Mysql:
+		prefix = "EXPLAIN FORMAT=JSON "
+	default:
+		return nil
+	}
+	var plan []byte
+	if err := target.QueryRowContext(context.Background(), prefix+query, args...).Scan(&plan); err != nil {
+		return nil
+	}
+	if !json.Valid(plan) {
+		return nil
+	}
+	return plan
+}
+
+func isExplainable(query string) bool {
+	return slices.Contains(explainableStatements, strings.ToLower(getOperation(query)))
+}
@@ -0,0 +1,196 @@
+package quirk
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	pg "github.com/lib/pq"
+)
+
+type HostOrder int
+
+type TopologyChange struct {
+	Previous string
+	Current  string
+}
+
+type failoverConnector struct {
+	dataSource string
+	hosts      []string
+	order      HostOrder
+	target     string
+	dialer     pg.Dialer
+	onChange   func(TopologyChange)
+	password   PasswordFunc
+	mu         sync.Mutex
+	current    string
+}
+
+type connectRetry struct {
+	attempts int
+	backoff  time.Duration
+	max      time.Duration
+}
+
+const (
+	HostOrderSequential HostOrder = iota
+	HostOrderRandom
+)
+
+const (
+	TargetSessionAny       = "any"
+	TargetSessionReadWrite = "read-write"
+	TargetSessionReadOnly  = "read-only"
+)
+
+var (
+	targetSessionAttrs = []string{TargetSessionAny, TargetSessionReadWrite, TargetSessionReadOnly}
+)
+
+const (
+	defaultPostgresPort     = "5432"
+	pgErrorReadOnlyTx       = "25006"
+	inRecoveryQuery         = "SELECT pg_is_in_recovery()"
+	defaultConnectRetryPing = 5 * time.Second
+)
+
+func (c *failoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	var errs error
+	for _, host := range c.orderedHosts() {
+		conn, err := c.connectHost(ctx, host)
+		if err != nil && len(host) > 0 {
+			err = fmt.Errorf("%s: %w", host, err)
+		}
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		c.setCurrent(host)
+		return wrapDriverConn(conn, isReadOnlyError), nil
+	}
+	return nil, fmt.Errorf("%w: %w", ErrorNoHostAvailable, errs)
+}
+
+func (c *failoverConnector) Driver() driver.Driver {
+	return &pg.Driver{}
+}
+
+func (c *failoverConnector) connectHost(ctx context.Context, address string) (driver.Conn, error) {
+	dataSource, err := c.hostDataSource(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := pg.NewConnector(dataSource)
+	if err != nil {
+		return nil, err
+	}
+	if c.dialer != nil {
+		pc.Dialer(c.dialer)
+	}
+	conn, err := pc.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c.target == TargetSessionAny || len(c.target) == 0 {
+		return conn, nil
+	}
+	value, err := queryDriverConnValue(ctx, conn, inRecoveryQuery)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	inRecovery, _ := value.(bool)
+	if inRecovery == (c.target == TargetSessionReadWrite) {
+		_ = conn.Close()
+		return nil, fmt.Errorf("%w: session is not %s", ErrorNoHostAvailable, c.target)
+	}
+	return conn, nil
+}
+
+func (c *failoverConnector) hostDataSource(ctx context.Context, address string) (string, error) {
+	dataSource, err := resolvePassword(ctx, c.dataSource, c.password)
+	if err != nil || len(address) == 0 {
+		return dataSource, err
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, defaultPostgresPort
+	}
+	props := newDataSource()
+	props.set(paramHost, host)
+	props.set(paramPort, port)
+	return dataSource + " " + props.String(), nil
+}
+
+func (c *failoverConnector) currentDataSource(ctx context.Context) (string, error) {
+	return c.hostDataSource(ctx, c.orderedHosts()[0])
+}
+
+func (c *failoverConnector) orderedHosts() []string {
+	if len(c.hosts) == 0 {
+		return []string{""}
+	}
+	hosts := slices.Clone(c.hosts)
+	if c.order == HostOrderRandom {
+		rand.Shuffle(
+			len(hosts), func(i, j int) {
+				hosts[i], hosts[j] = hosts[j], hosts[i]
+			},
+		)
+	}
+	c.mu.Lock()
+	current := c.current
+	c.mu.Unlock()
+	if i := slices.Index(hosts, current); i > 0 {
+		hosts = append([]string{current}, slices.Delete(hosts, i, i+1)...)
+	}
+	return hosts
+}
+
+func (c *failoverConnector) setCurrent(host string) {
+	c.mu.Lock()
+	previous := c.current
+	c.current = host
+	c.mu.Unlock()
+	if previous != host && c.onChange != nil {
+		c.onChange(TopologyChange{Previous: previous, Current: host})
+	}
+}
+
+func (d *DB) pingWithRetry(timeout time.Duration, retry connectRetry) error {
+	if timeout <= 0 {
+		timeout = defaultConnectRetryPing
+	}
+	backoff := retry.backoff
+	var err error
+	for attempt := 0; attempt < max(retry.attempts, 1); attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff = retry.next(backoff)
+		}
+		if err = d.ping(timeout); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (r connectRetry) next(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if r.max > 0 {
+		backoff = min(backoff, r.max)
+	}
+	return backoff
+}
+
+func isReadOnlyError(err error) bool {
+	var pgErr *pg.Error
+	return errors.As(err, &pgErr) && pgErr.Code == pgErrorReadOnlyTx
+}
@@ -0,0 +1,104 @@
+package quirk
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pg "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDriverConn struct {
+	driver.Conn
+	err error
+}
+
+func (c *fakeDriverConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return nil, c.err
+}
+
+func TestFailover(t *testing.T) {
+	t.Run(
+		"no reachable host", func(t *testing.T) {
+			changes := make([]TopologyChange, 0)
+			c := &failoverConnector{
+				dataSource: "sslmode=disable connect_timeout=1",
+				hosts:      []string{"127.0.0.1:1", "127.0.0.1:2"},
+				onChange: func(change TopologyChange) {
+					changes = append(changes, change)
+				},
+			}
+			_, err := c.Connect(context.Background())
+			assert.ErrorIs(t, err, ErrorNoHostAvailable)
+			assert.Empty(t, changes)
+		},
+	)
+	t.Run(
+		"current host first and topology hook", func(t *testing.T) {
+			changes := make([]TopologyChange, 0)
+			c := &failoverConnector{
+				hosts: []string{"a:5432", "b:5432", "c:5432"},
+				onChange: func(change TopologyChange) {
+					changes = append(changes, change)
+				},
+			}
+			c.setCurrent("a:5432")
+			c.setCurrent("b:5432")
+			c.setCurrent("b:5432")
+			assert.Equal(t, []string{"b:5432", "a:5432", "c:5432"}, c.orderedHosts())
+			assert.Equal(t, []TopologyChange{{Current: "a:5432"}, {Previous: "a:5432", Current: "b:5432"}}, changes)
+		},
+	)
+	t.Run(
+		"read-only error marks connection bad", func(t *testing.T) {
+			c := wrapDriverConn(&fakeDriverConn{err: &pg.Error{Code: pgErrorReadOnlyTx}}, isReadOnlyError)
+			assert.True(t, c.IsValid())
+			_, err := c.ExecContext(context.Background(), "UPDATE tests SET active = true", nil)
+			assert.NotNil(t, err)
+			assert.False(t, c.IsValid())
+			assert.ErrorIs(t, c.ResetSession(context.Background()), driver.ErrBadConn)
+		},
+	)
+	t.Run(
+		"startup retry with backoff", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+			assert.Nil(t, err)
+			mock.ExpectPing().WillReturnError(errors.New("starting up"))
+			mock.ExpectPing().WillReturnError(errors.New("starting up"))
+			mock.ExpectPing()
+			db := wrapConnection(sqlDB, Postgres)
+			assert.Nil(t, configureDB(db, WithConnectRetry(3, time.Millisecond, 2*time.Millisecond)))
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"backoff without cap", func(t *testing.T) {
+			retry := connectRetry{attempts: 3, backoff: time.Millisecond}
+			assert.Equal(t, 2*time.Millisecond, retry.next(time.Millisecond))
+			retry.max = 3 * time.Millisecond
+			assert.Equal(t, 3*time.Millisecond, retry.next(2*time.Millisecond))
+		},
+	)
+	t.Run(
+		"target session attrs on single host", func(t *testing.T) {
+			db, err := open(
+				Postgres, "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1",
+				WithTargetSessionAttrs(TargetSessionReadWrite),
+			)
+			assert.Nil(t, err)
+			assert.ErrorIs(t, db.Ping(), ErrorNoHostAvailable)
+			_, err = open(Mysql, "", WithTargetSessionAttrs(TargetSessionReadWrite))
+			assert.ErrorIs(t, err, ErrorInvalidConfig)
+		},
+	)
+	t.Run(
+		"invalid target session attrs", func(t *testing.T) {
+			_, err := Connect(WithPostgres(), WithHosts("a", "b"), WithTargetSessionAttrs("primary-ish"))
+			assert.ErrorIs(t, err, ErrorInvalidConfig)
+		},
+	)
+}
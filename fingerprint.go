@@ -0,0 +1,102 @@
+package quirk
+
+import (
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	fingerprintCommaMatcher   = regexp.MustCompile(`\s*,\s*`)
+	fingerprintOpeningMatcher = regexp.MustCompile(`([(\[])\s+`)
+	fingerprintClosingMatcher = regexp.MustCompile(`\s+([)\]])`)
+	fingerprintListMatcher    = regexp.MustCompile(`\(\?(?:, \?)*\)`)
+	fingerprintArrayMatcher   = regexp.MustCompile(`\[\?(?:, \?)*\]`)
+	fingerprintTupleMatcher   = regexp.MustCompile(`\(\?\)(?:, \(\?\))+`)
+)
+
+func Fingerprint(sql string) (string, uint64) {
+	normalized := formatSql(stripLiterals(sql))
+	normalized = fingerprintCommaMatcher.ReplaceAllString(normalized, ", ")
+	normalized = fingerprintOpeningMatcher.ReplaceAllString(normalized, "$1")
+	normalized = fingerprintClosingMatcher.ReplaceAllString(normalized, "$1")
+	normalized = fingerprintListMatcher.ReplaceAllString(normalized, "(?)")
+	normalized = fingerprintArrayMatcher.ReplaceAllString(normalized, "[?]")
+	normalized = fingerprintTupleMatcher.ReplaceAllString(normalized, "(?)")
+	normalized = strings.TrimSpace(strings.TrimSuffix(normalized, querySuffix))
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(normalized))
+	return normalized, h.Sum64()
+}
+
+func (q *Quirk) Fingerprint() (string, uint64) {
+	mergedQueryParts, _, err := processQueryParts(q)
+	if err != nil {
+		return "", 0
+	}
+	return Fingerprint(mergedQueryParts)
+}
+
+func stripLiterals(sql string) string {
+	var b strings.Builder
+	r := []rune(sql)
+	n := len(r)
+	for i := 0; i < n; i++ {
+		c := r[i]
+		switch {
+		case c == '-' && i+1 < n && r[i+1] == '-':
+			for i < n && r[i] != '\n' {
+				i++
+			}
+			b.WriteRune(' ')
+		case c == '/' && i+1 < n && r[i+1] == '*':
+			i += 2
+			for i < n && !(r[i] == '*' && i+1 < n && r[i+1] == '/') {
+				i++
+			}
+			i++
+			b.WriteRune(' ')
+		case c == '\'':
+			i++
+			for i < n {
+				if r[i] == '\'' && i+1 < n && r[i+1] == '\'' {
+					i += 2
+					continue
+				}
+				if r[i] == '\'' {
+					break
+				}
+				i++
+			}
+			b.WriteString(Placeholder)
+		case c == '"':
+			b.WriteRune(c)
+			for i++; i < n && r[i] != '"'; i++ {
+				b.WriteRune(r[i])
+			}
+			if i < n {
+				b.WriteRune(r[i])
+			}
+		case (c == '$' || c == rune(ParamPrefix[0])) && i+1 < n && isIdentifierRune(r[i+1]):
+			for i+1 < n && isIdentifierRune(r[i+1]) {
+				i++
+			}
+			b.WriteString(Placeholder)
+		case unicode.IsDigit(c) && (i == 0 || !isIdentifierRune(r[i-1])):
+			for i+1 < n && (unicode.IsDigit(r[i+1]) || r[i+1] == '.') {
+				i++
+			}
+			b.WriteString(Placeholder)
+		case (c == 'e' || c == 'E') && i+1 < n && r[i+1] == '\'' && (i == 0 || !isIdentifierRune(r[i-1])):
+			continue
+		default:
+			b.WriteRune(unicode.ToLower(c))
+		}
+	}
+	return b.String()
+}
+
+func isIdentifierRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
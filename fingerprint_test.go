@@ -0,0 +1,47 @@
+package quirk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	t.Run(
+		"strip literals and parameters", func(t *testing.T) {
+			normalized, _ := Fingerprint(
+				`SELECT * FROM "Tests" WHERE name = 'O''Brien' AND amount > 10.5 AND id = $12 AND note = E'x'`,
+			)
+			assert.Equal(t, `select * from "Tests" where name = ? and amount > ? and id = ? and note = ?`, normalized)
+		},
+	)
+	t.Run(
+		"collapse lists and whitespace", func(t *testing.T) {
+			a, ha := Fingerprint("SELECT id\n\tFROM tests WHERE id IN (1, 2, 3) AND roles && ARRAY['a','b'];")
+			b, hb := Fingerprint(`select id from tests where id in ( 7 ) and roles && array['c']`)
+			assert.Equal(t, `select id from tests where id in (?) and roles && array[?]`, a)
+			assert.Equal(t, a, b)
+			assert.Equal(t, ha, hb)
+		},
+	)
+	t.Run(
+		"collapse multi-row values", func(t *testing.T) {
+			normalized, _ := Fingerprint(`INSERT INTO tests (name, quantity) VALUES ('a', 1), ('b', 2)`)
+			assert.Equal(t, `insert into tests (name, quantity) values (?)`, normalized)
+		},
+	)
+	t.Run(
+		"ignore comments", func(t *testing.T) {
+			a, _ := Fingerprint("/* route='/users' */ SELECT 1 -- trailing\n FROM tests")
+			assert.Equal(t, `select ? from tests`, a)
+		},
+	)
+	t.Run(
+		"quirk fingerprint", func(t *testing.T) {
+			a, ha := New(&DB{}).Q(`SELECT * FROM tests WHERE id = @id`, Map{"id": 1}).Fingerprint()
+			_, hb := New(&DB{}).Q(`SELECT * FROM tests WHERE id = @id`, Map{"id": 2}).Fingerprint()
+			assert.Equal(t, `select * from tests where id = ?`, a)
+			assert.Equal(t, ha, hb)
+		},
+	)
+}
@@ -0,0 +1,44 @@
+package quirk
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type Health struct {
+	Latency time.Duration
+	Stats   sql.DBStats
+	Version string
+}
+
+const (
+	postgresVersionQuery = "SHOW server_version"
+	mysqlVersionQuery    = "SELECT VERSION()"
+)
+
+func (d *DB) Health(ctx context.Context) (Health, error) {
+	result := Health{}
+	t := time.Now()
+	if err := d.PingContext(ctx); err != nil {
+		result.Stats = d.Stats()
+		return result, err
+	}
+	result.Latency = time.Now().Sub(t)
+	query := postgresVersionQuery
+	if d.driverName == Mysql {
+		query = mysqlVersionQuery
+	}
+	if err := d.DB.QueryRowContext(ctx, query).Scan(&result.Version); err != nil {
+		result.Stats = d.Stats()
+		return result, err
+	}
+	result.Stats = d.Stats()
+	return result, nil
+}
+
+func (d *DB) ping(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d.PingContext(ctx)
+}
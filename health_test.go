@@ -0,0 +1,21 @@
+package quirk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	assert.Nil(t, err)
+	db := wrapConnection(sqlDB, Postgres)
+	mock.ExpectPing()
+	mock.ExpectQuery(postgresVersionQuery).WillReturnRows(sqlmock.NewRows([]string{"server_version"}).AddRow("16.2"))
+	health, err := db.Health(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "16.2", health.Version)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,65 @@
+package quirk
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+)
+
+type ConnectHook func(ctx context.Context, conn driver.Conn) error
+
+type hookConnector struct {
+	driver.Connector
+	hooks []ConnectHook
+}
+
+type dsnConnector struct {
+	dataSource string
+	driver     driver.Driver
+}
+
+func (c *hookConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range c.hooks {
+		if err := hook(ctx, conn); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dataSource)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func openConnector(driverName, dataSource string) (driver.Connector, error) {
+	db, err := sql.Open(driverName, dataSource)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	_ = db.Close()
+	if dc, ok := d.(driver.DriverContext); ok {
+		return dc.OpenConnector(dataSource)
+	}
+	return &dsnConnector{dataSource: dataSource, driver: d}, nil
+}
+
+func createInitSqlHook(statements ...string) ConnectHook {
+	return func(ctx context.Context, conn driver.Conn) error {
+		for _, statement := range statements {
+			if err := execDriverConn(ctx, conn, statement); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,57 @@
+package quirk
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectHooks(t *testing.T) {
+	t.Run(
+		"init sql and hook on new connection", func(t *testing.T) {
+			_, mock, err := sqlmock.NewWithDSN("quirk_hooks_init")
+			assert.Nil(t, err)
+			called := 0
+			db, err := open(
+				"sqlmock", "quirk_hooks_init",
+				WithInitSQL(`SET TIME ZONE 'UTC'`, `SET search_path TO app`),
+				WithOnConnect(
+					func(ctx context.Context, conn driver.Conn) error {
+						called++
+						return nil
+					},
+				),
+			)
+			assert.Nil(t, err)
+			mock.ExpectExec(`SET TIME ZONE 'UTC'`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`SET search_path TO app`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectPing()
+			assert.Nil(t, db.Ping())
+			assert.Nil(t, mock.ExpectationsWereMet())
+			assert.Equal(t, 1, called)
+		},
+	)
+	t.Run(
+		"failed hook closes connection", func(t *testing.T) {
+			_, mock, err := sqlmock.NewWithDSN("quirk_hooks_failure")
+			assert.Nil(t, err)
+			failure := errors.New("failure")
+			db, err := open(
+				"sqlmock", "quirk_hooks_failure",
+				WithOnConnect(
+					func(ctx context.Context, conn driver.Conn) error {
+						return failure
+					},
+				),
+			)
+			assert.Nil(t, err)
+			mock.ExpectClose()
+			assert.ErrorIs(t, db.Ping(), failure)
+			assert.Equal(t, 0, db.Stats().OpenConnections)
+		},
+	)
+}
@@ -0,0 +1,134 @@
+package quirk
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type LeaderElector struct {
+	db       *DB
+	key      any
+	interval time.Duration
+	onGained func(ctx context.Context)
+	onLost   func()
+	leader   atomic.Bool
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+const (
+	DefaultLeaderInterval = 5 * time.Second
+)
+
+func NewLeaderElector(db *DB, key any, interval ...time.Duration) *LeaderElector {
+	e := &LeaderElector{
+		db:       db,
+		key:      key,
+		interval: DefaultLeaderInterval,
+	}
+	if len(interval) > 0 && interval[0] > 0 {
+		e.interval = interval[0]
+	}
+	return e
+}
+
+// OnGained runs fn in its own goroutine while leadership is held. The context
+// is canceled once leadership is lost and fn must return promptly after that.
+func (e *LeaderElector) OnGained(fn func(ctx context.Context)) *LeaderElector {
+	e.onGained = fn
+	return e
+}
+
+func (e *LeaderElector) OnLost(fn func()) *LeaderElector {
+	e.onLost = fn
+	return e
+}
+
+func (e *LeaderElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+func (e *LeaderElector) Run(ctx context.Context) error {
+	for {
+		err := e.db.Session(
+			ctx, func(s *DB) error {
+				return e.campaign(ctx, s)
+			},
+		)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil && e.db.logger != nil {
+			e.db.logger.LogAttrs(ctx, slog.LevelWarn, "leader election", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(e.interval):
+		}
+	}
+}
+
+func (e *LeaderElector) campaign(ctx context.Context, s *DB) error {
+	defer e.resign()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		if e.IsLeader() {
+			if err := s.conn.PingContext(ctx); err != nil && ctx.Err() == nil {
+				return err
+			}
+		} else {
+			acquired, err := s.tryAdvisoryLock(ctx, advisoryTryLockQuery, e.key)
+			if err != nil && ctx.Err() == nil {
+				return err
+			}
+			if acquired {
+				e.gain(ctx)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if e.IsLeader() {
+				return s.advisoryUnlock(e.key)
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *LeaderElector) gain(ctx context.Context) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	e.mu.Lock()
+	e.cancel = cancel
+	e.mu.Unlock()
+	e.leader.Store(true)
+	if e.onGained != nil {
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			e.onGained(leaderCtx)
+		}()
+	}
+}
+
+func (e *LeaderElector) resign() {
+	if !e.leader.Swap(false) {
+		return
+	}
+	e.mu.Lock()
+	if e.cancel != nil {
+		e.cancel()
+		e.cancel = nil
+	}
+	e.mu.Unlock()
+	e.wg.Wait()
+	if e.onLost != nil {
+		e.onLost()
+	}
+}
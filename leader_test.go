@@ -0,0 +1,51 @@
+package quirk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderElector(t *testing.T) {
+	t.Run(
+		"lost on ping failure", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			key := AdvisoryLockKey("leader")
+			mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\);`).
+				WithArgs(key).
+				WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+			mock.ExpectPing().WillReturnError(errors.New("connection lost"))
+			mock.ExpectExec(postgresDiscardAll).WillReturnResult(sqlmock.NewResult(0, 0))
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			gained := make(chan struct{})
+			elector := NewLeaderElector(db, "leader", 10*time.Millisecond).
+				OnGained(
+					func(leaderCtx context.Context) {
+						close(gained)
+						<-leaderCtx.Done()
+					},
+				).
+				OnLost(cancel)
+			done := make(chan error)
+			go func() {
+				done <- elector.Run(ctx)
+			}()
+			select {
+			case err := <-done:
+				assert.Nil(t, err)
+			case <-time.After(time.Second):
+				t.Fatal("leader kept running after ping failure")
+			}
+			<-gained
+			assert.False(t, elector.IsLeader())
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+}
@@ -0,0 +1,175 @@
+package quirk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	pg "github.com/lib/pq"
+)
+
+type Notification struct {
+	Channel     string
+	Payload     string
+	Pid         int
+	Reconnected bool
+}
+
+const (
+	listenMinReconnect = time.Second
+	listenMaxReconnect = time.Minute
+	notifyQuery        = "SELECT pg_notify(@channel, @payload)"
+)
+
+func (d *DB) Listen(ctx context.Context, channels ...string) (<-chan Notification, error) {
+	if d.driverName != Postgres || d.dataSource == nil {
+		return nil, fmt.Errorf("%w: listen requires %s connection", ErrorInvalidConfig, Postgres)
+	}
+	listener, err := d.connectListener(ctx, false, channels...)
+	if err != nil {
+		return nil, err
+	}
+	notifications := make(chan Notification)
+	go func() {
+		defer close(notifications)
+		for {
+			forwardNotifications(ctx, listener.Notify, notifications)
+			_ = listener.Close()
+			if listener, err = d.connectListener(ctx, true, channels...); err != nil {
+				return
+			}
+			select {
+			case notifications <- Notification{Reconnected: true}:
+			case <-ctx.Done():
+				_ = listener.Close()
+				return
+			}
+		}
+	}()
+	return notifications, nil
+}
+
+// connectListener rebuilds the listener from a freshly resolved data source after every failed
+// connection attempt, so a rotated password is picked up instead of retrying the old one.
+func (d *DB) connectListener(ctx context.Context, retryAll bool, channels ...string) (*pg.Listener, error) {
+	backoff := listenMinReconnect
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		listener, failed, err := d.openListener(ctx, channels...)
+		if err == nil {
+			return listener, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !failed && !retryAll {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, listenMaxReconnect)
+	}
+}
+
+func (d *DB) openListener(ctx context.Context, channels ...string) (*pg.Listener, bool, error) {
+	dataSource, err := d.dataSource(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	var listener *pg.Listener
+	var failed atomic.Bool
+	ready := make(chan struct{})
+	event := func(event pg.ListenerEventType, err error) {
+		d.logListenerEvent(event, err)
+		if event == pg.ListenerEventConnectionAttemptFailed && !failed.Swap(true) {
+			go func() {
+				<-ready
+				_ = listener.Close()
+			}()
+		}
+	}
+	if d.dialer != nil {
+		listener = pg.NewDialListener(d.dialer, dataSource, listenMinReconnect, listenMaxReconnect, event)
+	} else {
+		listener = pg.NewListener(dataSource, listenMinReconnect, listenMaxReconnect, event)
+	}
+	close(ready)
+	stop := context.AfterFunc(
+		ctx, func() {
+			_ = listener.Close()
+		},
+	)
+	defer stop()
+	for _, channel := range channels {
+		if err := listener.Listen(channel); err != nil {
+			_ = listener.Close()
+			return nil, failed.Load(), err
+		}
+	}
+	return listener, false, nil
+}
+
+func (d *DB) Notify(channel string, payload any) error {
+	var p string
+	switch v := payload.(type) {
+	case string:
+		p = v
+	case []byte:
+		p = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		p = string(b)
+	}
+	return d.Q(notifyQuery, Map{"channel": channel, "payload": p}).Primary().Exec()
+}
+
+func DecodeNotification[T any](n Notification) (T, error) {
+	var result T
+	err := json.Unmarshal([]byte(n.Payload), &result)
+	return result, err
+}
+
+func forwardNotifications(ctx context.Context, in <-chan *pg.Notification, out chan<- Notification) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n, ok := <-in:
+			if !ok {
+				return
+			}
+			notification := Notification{Reconnected: true}
+			if n != nil {
+				notification = Notification{Channel: n.Channel, Payload: n.Extra, Pid: n.BePid}
+			}
+			select {
+			case out <- notification:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (d *DB) logListenerEvent(event pg.ListenerEventType, err error) {
+	if d.logger == nil {
+		return
+	}
+	switch event {
+	case pg.ListenerEventDisconnected, pg.ListenerEventConnectionAttemptFailed:
+		d.logger.LogAttrs(context.Background(), slog.LevelWarn, "listener disconnected", slog.Any("error", err))
+	case pg.ListenerEventReconnected:
+		d.logger.LogAttrs(context.Background(), slog.LevelInfo, "listener reconnected")
+	}
+}
@@ -0,0 +1,129 @@
+package quirk
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pg "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListen(t *testing.T) {
+	t.Run(
+		"notify", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			mock.ExpectQuery(`SELECT pg_notify\(\$1, \$2\);`).
+				WithArgs("cache", `{"id":1}`).
+				WillReturnRows(sqlmock.NewRows([]string{"pg_notify"}).AddRow(""))
+			assert.Nil(t, db.Notify("cache", map[string]int{"id": 1}))
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"notify on primary in cluster", func(t *testing.T) {
+			primaryDB, primaryMock, err := sqlmock.New()
+			assert.Nil(t, err)
+			replicaDB, replicaMock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := NewCluster(wrapConnection(primaryDB, Postgres), wrapConnection(replicaDB, Postgres))
+			primaryMock.ExpectQuery(`SELECT pg_notify\(\$1, \$2\);`).
+				WithArgs("cache", "users").
+				WillReturnRows(sqlmock.NewRows([]string{"pg_notify"}).AddRow(""))
+			assert.Nil(t, db.Notify("cache", "users"))
+			assert.Nil(t, primaryMock.ExpectationsWereMet())
+			assert.Nil(t, replicaMock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"reconnect signal", func(t *testing.T) {
+			in := make(chan *pg.Notification, 2)
+			out := make(chan Notification)
+			in <- &pg.Notification{Channel: "cache", Extra: "users", BePid: 42}
+			in <- nil
+			close(in)
+			done := make(chan struct{})
+			go func() {
+				forwardNotifications(context.Background(), in, out)
+				close(done)
+			}()
+			assert.Equal(t, Notification{Channel: "cache", Payload: "users", Pid: 42}, <-out)
+			assert.Equal(t, Notification{Reconnected: true}, <-out)
+			<-done
+		},
+	)
+	t.Run(
+		"reconnect with rotated password", func(t *testing.T) {
+			server, err := net.Listen("tcp", "127.0.0.1:0")
+			assert.Nil(t, err)
+			defer func() {
+				_ = server.Close()
+			}()
+			passwords := make(chan string, 10)
+			go acceptPasswordAuth(server, passwords)
+			host, port, _ := net.SplitHostPort(server.Addr().String())
+			secrets := []string{"first", "rotated"}
+			var calls atomic.Int32
+			db := wrapConnection(nil, Postgres)
+			db.dataSource = func(ctx context.Context) (string, error) {
+				return resolvePassword(
+					ctx, fmt.Sprintf("host=%s port=%s user=app sslmode=disable connect_timeout=1", host, port),
+					func(ctx context.Context) (string, error) {
+						return secrets[min(int(calls.Add(1)), len(secrets))-1], nil
+					},
+				)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() {
+				_, err := db.Listen(ctx, "jobs")
+				done <- err
+			}()
+			assert.Equal(t, "first", <-passwords)
+			for password := range passwords {
+				if password == "rotated" {
+					break
+				}
+			}
+			cancel()
+			assert.ErrorIs(t, <-done, context.Canceled)
+		},
+	)
+	t.Run(
+		"decode payload", func(t *testing.T) {
+			type invalidation struct {
+				Id  int    `json:"id"`
+				Key string `json:"key"`
+			}
+			result, err := DecodeNotification[invalidation](Notification{Payload: `{"id":1,"key":"users"}`})
+			assert.Nil(t, err)
+			assert.Equal(t, invalidation{Id: 1, Key: "users"}, result)
+			_, err = DecodeNotification[invalidation](Notification{Payload: `invalid`})
+			assert.NotNil(t, err)
+		},
+	)
+	t.Run(
+		"listen requires postgres connection", func(t *testing.T) {
+			sqlDB, _, err := sqlmock.New()
+			assert.Nil(t, err)
+			_, err = wrapConnection(sqlDB, Postgres).Listen(context.Background(), "cache")
+			assert.ErrorIs(t, err, ErrorInvalidConfig)
+		},
+	)
+	t.Run(
+		"listen canceled while connecting", func(t *testing.T) {
+			db, err := open(Postgres, "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+			assert.Nil(t, err)
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err = db.Listen(ctx, "cache")
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		},
+	)
+}
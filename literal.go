@@ -0,0 +1,192 @@
+package quirk
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	pg "github.com/lib/pq"
+)
+
+type Secret string
+
+type redactedArg struct {
+	value any
+}
+
+const (
+	redactedValue   = "***"
+	literalNull     = "NULL"
+	timestampLayout = "2006-01-02 15:04:05.999999Z07:00"
+)
+
+var (
+	postgresStringEscaper = strings.NewReplacer("'", "''")
+	mysqlStringEscaper    = strings.NewReplacer(`\`, `\\`, "'", "''")
+)
+
+func (d *DB) Redact(names ...string) {
+	for _, name := range names {
+		d.redacted = append(d.redacted, strings.ToLower(name))
+	}
+}
+
+func (s Secret) String() string {
+	return redactedValue
+}
+
+func (s Secret) GoString() string {
+	return redactedValue
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(redactedValue)
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redactedValue)
+}
+
+func (r redactedArg) Value() (driver.Value, error) {
+	return driver.DefaultParameterConverter.ConvertValue(r.value)
+}
+
+func (r redactedArg) String() string {
+	return redactedValue
+}
+
+func (r redactedArg) GoString() string {
+	return redactedValue
+}
+
+func (r redactedArg) LogValue() slog.Value {
+	return slog.StringValue(redactedValue)
+}
+
+func (r redactedArg) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redactedValue)
+}
+
+func redactArgs(args []any) []any {
+	result := make([]any, len(args))
+	for i, arg := range args {
+		switch arg.(type) {
+		case Secret, redactedArg:
+			result[i] = redactedValue
+		default:
+			result[i] = arg
+		}
+	}
+	return result
+}
+
+func formatLiteral(driverName string, value any) string {
+	switch v := value.(type) {
+	case nil:
+		return literalNull
+	case Secret, redactedArg:
+		return quoteLiteral(driverName, redactedValue)
+	case Safe:
+		return string(v)
+	case string:
+		return quoteLiteral(driverName, v)
+	case []byte:
+		if driverName == Mysql {
+			return "X'" + hex.EncodeToString(v) + "'"
+		}
+		return `'\x` + hex.EncodeToString(v) + "'"
+	case json.RawMessage:
+		return quoteLiteral(driverName, string(v))
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case time.Time:
+		return quoteLiteral(driverName, v.Format(timestampLayout))
+	case pg.GenericArray:
+		return formatArrayLiteral(driverName, v.A)
+	case *pg.StringArray:
+		return formatArrayLiteral(driverName, []string(*v))
+	case *pg.Int64Array:
+		return formatArrayLiteral(driverName, []int64(*v))
+	case *pg.Int32Array:
+		return formatArrayLiteral(driverName, []int32(*v))
+	case *pg.Float64Array:
+		return formatArrayLiteral(driverName, []float64(*v))
+	case *pg.Float32Array:
+		return formatArrayLiteral(driverName, []float32(*v))
+	case *pg.BoolArray:
+		return formatArrayLiteral(driverName, []bool(*v))
+	case *pg.ByteaArray:
+		return formatArrayLiteral(driverName, [][]byte(*v))
+	case driver.Valuer:
+		dv, err := v.Value()
+		if err != nil {
+			return quoteLiteral(driverName, fmt.Sprintf("%v", v))
+		}
+		if b, ok := dv.([]byte); ok && utf8.Valid(b) {
+			return quoteLiteral(driverName, string(b))
+		}
+		return formatLiteral(driverName, dv)
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return literalNull
+		}
+		return formatLiteral(driverName, rv.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64)
+	case reflect.String:
+		return quoteLiteral(driverName, rv.String())
+	case reflect.Bool:
+		return formatLiteral(driverName, rv.Bool())
+	case reflect.Slice, reflect.Array:
+		return formatArrayLiteral(driverName, value)
+	case reflect.Map, reflect.Struct:
+		b, err := json.Marshal(value)
+		if err == nil {
+			return quoteLiteral(driverName, string(b))
+		}
+	}
+	return quoteLiteral(driverName, fmt.Sprintf("%v", value))
+}
+
+func formatArrayLiteral(driverName string, value any) string {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return formatLiteral(driverName, value)
+	}
+	n := rv.Len()
+	items := make([]string, n)
+	for i := 0; i < n; i++ {
+		items[i] = formatLiteral(driverName, rv.Index(i).Interface())
+	}
+	if driverName == Mysql {
+		return "(" + strings.Join(items, ", ") + ")"
+	}
+	if n == 0 {
+		return "'{}'"
+	}
+	return "ARRAY[" + strings.Join(items, ", ") + "]"
+}
+
+func quoteLiteral(driverName string, value string) string {
+	if driverName == Mysql {
+		return "'" + mysqlStringEscaper.Replace(value) + "'"
+	}
+	return "'" + postgresStringEscaper.Replace(value) + "'"
+}
@@ -0,0 +1,72 @@
+package quirk
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pg "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiteral(t *testing.T) {
+	t.Run(
+		"postgres literals", func(t *testing.T) {
+			created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			assert.Equal(
+				t,
+				`SELECT 'O''Brien', 1.5, TRUE, NULL, '2024-01-02 03:04:05Z', ARRAY['a', 'b'], ARRAY[1, 2], '{"a":1}';`,
+				createQueryLog(
+					Postgres, `SELECT $1, $2, $3, $4, $5, $6, $7, $8;`,
+					"O'Brien", 1.5, true, nil, created, pg.Array([]string{"a", "b"}), pg.Array([]int{1, 2}),
+					MapToJsonb(map[string]int{"a": 1}),
+				),
+			)
+		},
+	)
+	t.Run(
+		"placeholders above nine", func(t *testing.T) {
+			args := []any{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+			assert.Equal(
+				t,
+				`SELECT 1, 10, '$1';`,
+				createQueryLog(Postgres, `SELECT $1, $10, '$1';`, args...),
+			)
+		},
+	)
+	t.Run(
+		"mysql literals", func(t *testing.T) {
+			assert.Equal(
+				t, `SELECT 'a\\b''c', '?';`, createQueryLog(Mysql, `SELECT ?, '?';`, `a\b'c`),
+			)
+		},
+	)
+	t.Run(
+		"secret", func(t *testing.T) {
+			assert.Equal(t, `SELECT '***';`, createQueryLog(Postgres, `SELECT $1;`, Secret("password")))
+			b, err := json.Marshal([]any{Secret("password")})
+			assert.Nil(t, err)
+			assert.Equal(t, `["***"]`, string(b))
+		},
+	)
+	t.Run(
+		"redact by parameter name", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			db.Redact("Token")
+			var event QueryEvent
+			db.Subscribe(
+				func(e QueryEvent) {
+					event = e
+				},
+			)
+			q := db.Q(`UPDATE users SET token = @token WHERE id = @id`, Map{"token": "abc", "id": 1})
+			mock.ExpectQuery(q.CreateMatcher()).WithArgs("abc", 1).WillReturnRows(sqlmock.NewRows(nil))
+			assert.Nil(t, q.Exec())
+			assert.Nil(t, mock.ExpectationsWereMet())
+			assert.Equal(t, `UPDATE users SET token = '***' WHERE id = 1;`, event.Sql)
+		},
+	)
+}
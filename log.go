@@ -1,27 +1,87 @@
 package quirk
 
 import (
-	"fmt"
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
 	"strings"
-	"time"
 )
 
-func log(use bool, q string, d time.Duration) {
-	if !use {
+func createDefaultLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+func (d *DB) logQuery(event QueryEvent) {
+	if d.logger == nil {
 		return
 	}
-	fmt.Printf("[%s] %s\n", d.String(), q)
+	level := slog.LevelDebug
+	if d.isSlowQuery(event.Duration) {
+		level = slog.LevelWarn
+	}
+	if event.Err != nil {
+		level = slog.LevelError
+	}
+	ctx := context.Background()
+	if !d.logger.Enabled(ctx, level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("query", formatSql(event.Query)),
+		slog.Any("args", redactArgs(event.Args)),
+		slog.Duration("duration", event.Duration),
+		slog.Int("rows", event.Rows),
+		slog.Bool("transaction", event.TxDepth > 0),
+		slog.String("driver", event.Dialect),
+	}
+	if event.Err != nil {
+		attrs = append(attrs, slog.String("error", event.Err.Error()))
+	}
+	if len(event.Plan) > 0 {
+		attrs = append(attrs, slog.Any("plan", event.Plan))
+	}
+	d.logger.LogAttrs(ctx, level, "query", attrs...)
 }
 
 func createQueryLog(driverName string, q string, args ...any) string {
 	q = formatSql(q)
-	for i, a := range args {
-		switch driverName {
-		case Postgres:
-			q = strings.Replace(q, fmt.Sprintf("$%d", i+1), fmt.Sprintf("%v", a), 1)
-		case Mysql:
-			q = strings.Replace(q, "?", fmt.Sprintf("%v", a), 1)
+	var b strings.Builder
+	quoted := false
+	next := 0
+	for i := 0; i < len(q); i++ {
+		c := q[i]
+		if c == '\'' {
+			quoted = !quoted
+		}
+		if quoted {
+			b.WriteByte(c)
+			continue
+		}
+		switch {
+		case driverName == Postgres && c == '$' && i+1 < len(q) && isDigit(q[i+1]):
+			j := i + 1
+			for j < len(q) && isDigit(q[j]) {
+				j++
+			}
+			index, err := strconv.Atoi(q[i+1 : j])
+			if err != nil || index < 1 || index > len(args) {
+				b.WriteString(q[i:j])
+				i = j - 1
+				continue
+			}
+			b.WriteString(formatLiteral(driverName, args[index-1]))
+			i = j - 1
+		case driverName == Mysql && c == '?' && next < len(args):
+			b.WriteString(formatLiteral(driverName, args[next]))
+			next++
+		default:
+			b.WriteByte(c)
 		}
 	}
-	return q
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
 }
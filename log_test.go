@@ -0,0 +1,113 @@
+package quirk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog(t *testing.T) {
+	createLoggedDB := func(t *testing.T) (*DB, sqlmock.Sqlmock, *bytes.Buffer) {
+		sqlDB, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+		buf := new(bytes.Buffer)
+		db := wrapConnection(sqlDB, Postgres)
+		db.Logger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+		return db, mock, buf
+	}
+	readRecord := func(t *testing.T, buf *bytes.Buffer) map[string]any {
+		record := make(map[string]any)
+		assert.Nil(t, json.Unmarshal(buf.Bytes(), &record))
+		return record
+	}
+	t.Run(
+		"debug record", func(t *testing.T) {
+			db, mock, buf := createLoggedDB(t)
+			q := New(db).Q(`SELECT id FROM tests WHERE id = @id`, Map{"id": 1})
+			mock.ExpectQuery(q.CreateMatcher()).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			var id int
+			assert.Nil(t, q.Exec(&id))
+			record := readRecord(t, buf)
+			assert.Equal(t, "DEBUG", record["level"])
+			assert.Equal(t, "SELECT id FROM tests WHERE id = $1;", record["query"])
+			assert.Equal(t, float64(1), record["rows"])
+			assert.Equal(t, false, record["transaction"])
+			assert.Equal(t, Postgres, record["driver"])
+		},
+	)
+	t.Run(
+		"error record", func(t *testing.T) {
+			db, mock, buf := createLoggedDB(t)
+			q := New(db).Q(`SELECT id FROM tests`)
+			mock.ExpectQuery(q.CreateMatcher()).WillReturnError(errors.New("boom"))
+			assert.NotNil(t, q.Exec())
+			record := readRecord(t, buf)
+			assert.Equal(t, "ERROR", record["level"])
+			assert.Equal(t, "boom", record["error"])
+		},
+	)
+	t.Run(
+		"slow threshold disabled by default", func(t *testing.T) {
+			db, mock, buf := createLoggedDB(t)
+			q := New(db).Q(`SELECT id FROM tests`)
+			mock.ExpectQuery(q.CreateMatcher()).WillDelayFor(5 * time.Millisecond).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			assert.Nil(t, q.Exec())
+			assert.Nil(t, mock.ExpectationsWereMet())
+			assert.Equal(t, "DEBUG", readRecord(t, buf)["level"])
+		},
+	)
+	t.Run(
+		"slow record with plan", func(t *testing.T) {
+			db, mock, buf := createLoggedDB(t)
+			db.SlowQueryThreshold(time.Millisecond)
+			q := New(db).Q(`SELECT id FROM tests WHERE id = @id`, Map{"id": 1})
+			mock.ExpectQuery(q.CreateMatcher()).
+				WithArgs(1).
+				WillDelayFor(5 * time.Millisecond).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN (FORMAT JSON) " + q.CreateSql())).
+				WithArgs(1).
+				WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow([]byte(`[{"Plan":{}}]`)))
+			assert.Nil(t, q.Exec())
+			assert.Nil(t, mock.ExpectationsWereMet())
+			record := readRecord(t, buf)
+			assert.Equal(t, "WARN", record["level"])
+			assert.Equal(t, []any{map[string]any{"Plan": map[string]any{}}}, record["plan"])
+		},
+	)
+	t.Run(
+		"slow replica query explained on replica", func(t *testing.T) {
+			db, primaryMock, buf := createLoggedDB(t)
+			replicaDB, replicaMock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db = NewCluster(db, wrapConnection(replicaDB, Postgres))
+			db.SlowQueryThreshold(time.Millisecond)
+			q := New(db).Q(`SELECT id FROM tests`)
+			replicaMock.ExpectQuery(q.CreateMatcher()).WillDelayFor(5 * time.Millisecond).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			replicaMock.ExpectQuery(regexp.QuoteMeta("EXPLAIN (FORMAT JSON) " + q.CreateSql())).
+				WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow([]byte(`[{"Plan":{}}]`)))
+			assert.Nil(t, q.Exec())
+			assert.Nil(t, primaryMock.ExpectationsWereMet())
+			assert.Nil(t, replicaMock.ExpectationsWereMet())
+			assert.NotNil(t, readRecord(t, buf)["plan"])
+		},
+	)
+	t.Run(
+		"slow write is not explained", func(t *testing.T) {
+			db, mock, buf := createLoggedDB(t)
+			db.SlowQueryThreshold(time.Millisecond)
+			q := New(db).Q(`CREATE TABLE tests (id serial)`)
+			mock.ExpectQuery(q.CreateMatcher()).WillDelayFor(5 * time.Millisecond).WillReturnRows(sqlmock.NewRows(nil))
+			assert.Nil(t, q.Exec())
+			assert.Nil(t, mock.ExpectationsWereMet())
+			assert.Nil(t, readRecord(t, buf)["plan"])
+		},
+	)
+}
@@ -0,0 +1,187 @@
+package quirk
+
+import (
+	"bufio"
+	"cmp"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Metrics struct {
+	db      *DB
+	buckets []float64
+	mu      sync.Mutex
+	queries map[string]*QueryMetrics
+}
+
+type QueryMetrics struct {
+	Fingerprint string
+	Count       uint64
+	Errors      uint64
+	Sum         time.Duration
+	Buckets     []Bucket
+}
+
+type Bucket struct {
+	UpperBound float64
+	Count      uint64
+}
+
+type MetricsSnapshot struct {
+	Queries []QueryMetrics
+	Pool    sql.DBStats
+}
+
+const (
+	metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+	metricsNamespace   = "quirk"
+)
+
+var (
+	DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+)
+
+var (
+	metricsLabelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+func NewMetrics(db *DB, buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	m := &Metrics{
+		db:      db,
+		buckets: buckets,
+		queries: make(map[string]*QueryMetrics),
+	}
+	db.Subscribe(m.observe)
+	return m
+}
+
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	queries := make([]QueryMetrics, 0, len(m.queries))
+	for _, qm := range m.queries {
+		item := *qm
+		item.Buckets = slices.Clone(qm.Buckets)
+		queries = append(queries, item)
+	}
+	m.mu.Unlock()
+	slices.SortFunc(
+		queries, func(a, b QueryMetrics) int {
+			return cmp.Compare(a.Fingerprint, b.Fingerprint)
+		},
+	)
+	return MetricsSnapshot{
+		Queries: queries,
+		Pool:    m.db.Stats(),
+	}
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	bw := bufio.NewWriter(w)
+	writeMetrics(bw, m.Snapshot())
+	_ = bw.Flush()
+}
+
+func (m *Metrics) observe(event QueryEvent) {
+	fingerprint, _ := Fingerprint(event.Query)
+	seconds := event.Duration.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	qm, ok := m.queries[fingerprint]
+	if !ok {
+		qm = &QueryMetrics{Fingerprint: fingerprint, Buckets: make([]Bucket, len(m.buckets))}
+		for i, b := range m.buckets {
+			qm.Buckets[i].UpperBound = b
+		}
+		m.queries[fingerprint] = qm
+	}
+	qm.Count++
+	qm.Sum += event.Duration
+	if event.Err != nil {
+		qm.Errors++
+	}
+	for i := range qm.Buckets {
+		if seconds <= qm.Buckets[i].UpperBound {
+			qm.Buckets[i].Count++
+		}
+	}
+}
+
+func writeMetrics(w *bufio.Writer, snapshot MetricsSnapshot) {
+	writeMetricHeader(w, "queries_total", "counter", "Total number of executed queries.")
+	for _, qm := range snapshot.Queries {
+		writeMetricValue(w, "queries_total", createFingerprintLabel(qm.Fingerprint), qm.Count)
+	}
+	writeMetricHeader(w, "query_errors_total", "counter", "Total number of failed queries.")
+	for _, qm := range snapshot.Queries {
+		writeMetricValue(w, "query_errors_total", createFingerprintLabel(qm.Fingerprint), qm.Errors)
+	}
+	writeMetricHeader(w, "query_duration_seconds", "histogram", "Query execution duration in seconds.")
+	for _, qm := range snapshot.Queries {
+		label := createFingerprintLabel(qm.Fingerprint)
+		for _, b := range qm.Buckets {
+			writeMetricValue(
+				w, "query_duration_seconds_bucket",
+				fmt.Sprintf(`%s,le="%s"`, label, strconv.FormatFloat(b.UpperBound, 'g', -1, 64)),
+				b.Count,
+			)
+		}
+		writeMetricValue(w, "query_duration_seconds_bucket", label+`,le="+Inf"`, qm.Count)
+		writeMetricValue(w, "query_duration_seconds_sum", label, qm.Sum.Seconds())
+		writeMetricValue(w, "query_duration_seconds_count", label, qm.Count)
+	}
+	pool := snapshot.Pool
+	writePoolMetric(w, "pool_max_open_connections", "gauge", "Maximum number of open connections.", pool.MaxOpenConnections)
+	writePoolMetric(w, "pool_open_connections", "gauge", "Number of established connections.", pool.OpenConnections)
+	writePoolMetric(w, "pool_in_use_connections", "gauge", "Number of connections currently in use.", pool.InUse)
+	writePoolMetric(w, "pool_idle_connections", "gauge", "Number of idle connections.", pool.Idle)
+	writePoolMetric(w, "pool_wait_count_total", "counter", "Total number of connections waited for.", pool.WaitCount)
+	writePoolMetric(
+		w, "pool_wait_duration_seconds_total", "counter", "Total time blocked waiting for a new connection.",
+		pool.WaitDuration.Seconds(),
+	)
+	writePoolMetric(
+		w, "pool_max_idle_closed_total", "counter", "Total number of connections closed due to max idle count.",
+		pool.MaxIdleClosed,
+	)
+	writePoolMetric(
+		w, "pool_max_idle_time_closed_total", "counter", "Total number of connections closed due to max idle time.",
+		pool.MaxIdleTimeClosed,
+	)
+	writePoolMetric(
+		w, "pool_max_lifetime_closed_total", "counter", "Total number of connections closed due to max lifetime.",
+		pool.MaxLifetimeClosed,
+	)
+}
+
+func writeMetricHeader(w *bufio.Writer, name, metricType, help string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s_%s %s\n", metricsNamespace, name, help)
+	_, _ = fmt.Fprintf(w, "# TYPE %s_%s %s\n", metricsNamespace, name, metricType)
+}
+
+func writeMetricValue(w *bufio.Writer, name, labels string, value any) {
+	if len(labels) > 0 {
+		labels = "{" + labels + "}"
+	}
+	_, _ = fmt.Fprintf(w, "%s_%s%s %v\n", metricsNamespace, name, labels, value)
+}
+
+func writePoolMetric(w *bufio.Writer, name, metricType, help string, value any) {
+	writeMetricHeader(w, name, metricType, help)
+	writeMetricValue(w, name, "", value)
+}
+
+func createFingerprintLabel(fingerprint string) string {
+	return fmt.Sprintf(`fingerprint="%s"`, metricsLabelValueReplacer.Replace(fingerprint))
+}
@@ -0,0 +1,51 @@
+package quirk
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	db := wrapConnection(sqlDB, Postgres)
+	metrics := NewMetrics(db, 0.5, 1)
+	for _, id := range []int{1, 2} {
+		q := db.Q(`SELECT name FROM tests WHERE id = @id`, Map{"id": id})
+		mock.ExpectQuery(q.CreateMatcher()).WithArgs(id).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+		assert.Nil(t, q.Exec())
+	}
+	failing := db.Q(`DELETE FROM tests WHERE name = 'test'`)
+	mock.ExpectQuery(failing.CreateMatcher()).WillReturnError(errors.New("boom"))
+	assert.NotNil(t, failing.Exec())
+	t.Run(
+		"snapshot", func(t *testing.T) {
+			snapshot := metrics.Snapshot()
+			assert.Len(t, snapshot.Queries, 2)
+			assert.Equal(t, "delete from tests where name = ?", snapshot.Queries[0].Fingerprint)
+			assert.Equal(t, uint64(1), snapshot.Queries[0].Errors)
+			assert.Equal(t, "select name from tests where id = ?", snapshot.Queries[1].Fingerprint)
+			assert.Equal(t, uint64(2), snapshot.Queries[1].Count)
+			assert.Equal(t, uint64(0), snapshot.Queries[1].Errors)
+			assert.Equal(t, []Bucket{{UpperBound: 0.5, Count: 2}, {UpperBound: 1, Count: 2}}, snapshot.Queries[1].Buckets)
+		},
+	)
+	t.Run(
+		"prometheus handler", func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+			body := recorder.Body.String()
+			assert.Equal(t, metricsContentType, recorder.Header().Get("Content-Type"))
+			assert.Contains(t, body, "# TYPE quirk_queries_total counter\n")
+			assert.Contains(t, body, `quirk_queries_total{fingerprint="select name from tests where id = ?"} 2`)
+			assert.Contains(t, body, `quirk_query_errors_total{fingerprint="delete from tests where name = ?"} 1`)
+			assert.Contains(t, body, `quirk_query_duration_seconds_bucket{fingerprint="select name from tests where id = ?",le="0.5"} 2`)
+			assert.Contains(t, body, `quirk_query_duration_seconds_bucket{fingerprint="select name from tests where id = ?",le="+Inf"} 2`)
+			assert.Contains(t, body, "quirk_pool_open_connections ")
+		},
+	)
+}
@@ -0,0 +1,86 @@
+package quirk
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Rows interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...any) error
+	Close() error
+	Err() error
+}
+
+type Executor func(ctx context.Context, query string, args []any) (Rows, error)
+
+type Middleware func(next Executor) Executor
+
+type cancelRows struct {
+	Rows
+	cancel context.CancelFunc
+}
+
+var (
+	readStatements = []string{"select", "show", "explain", "values", "table"}
+)
+
+func (d *DB) Use(middlewares ...Middleware) {
+	d.middlewares = append(d.middlewares, middlewares...)
+}
+
+func (d *DB) executor(primary bool, target *querier) Executor {
+	var exec Executor = func(ctx context.Context, query string, args []any) (Rows, error) {
+		if r := d.selectReplica(query, primary); r != nil {
+			*target = r.DB
+			rows, err := r.DB.QueryContext(ctx, query, args...)
+			if err == nil || !isConnectionError(err) {
+				return rows, err
+			}
+			r.markDown(d.cluster.downtime)
+		}
+		*target = d.querier()
+		return d.querier().QueryContext(ctx, query, args...)
+	}
+	for i := len(d.middlewares) - 1; i >= 0; i-- {
+		exec = d.middlewares[i](exec)
+	}
+	return exec
+}
+
+func TimeoutMiddleware(timeout time.Duration) Middleware {
+	return func(next Executor) Executor {
+		return func(ctx context.Context, query string, args []any) (Rows, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			rows, err := next(ctx, query, args)
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			return &cancelRows{Rows: rows, cancel: cancel}, nil
+		}
+	}
+}
+
+func ReadOnlyMiddleware() Middleware {
+	return func(next Executor) Executor {
+		return func(ctx context.Context, query string, args []any) (Rows, error) {
+			if !isReadQuery(query) {
+				return nil, ErrorReadOnly
+			}
+			return next(ctx, query, args)
+		}
+	}
+}
+
+func (r *cancelRows) Close() error {
+	defer r.cancel()
+	return r.Rows.Close()
+}
+
+func isReadQuery(query string) bool {
+	return slices.Contains(readStatements, strings.ToLower(getOperation(query)))
+}
@@ -0,0 +1,87 @@
+package quirk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type cachedRows struct {
+	values []int
+	index  int
+}
+
+func (r *cachedRows) Columns() ([]string, error) {
+	return []string{"id"}, nil
+}
+
+func (r *cachedRows) Next() bool {
+	r.index++
+	return r.index <= len(r.values)
+}
+
+func (r *cachedRows) Scan(dest ...any) error {
+	*(dest[0].(*int)) = r.values[r.index-1]
+	return nil
+}
+
+func (r *cachedRows) Close() error {
+	return nil
+}
+
+func (r *cachedRows) Err() error {
+	return nil
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run(
+		"rewrite query in registration order", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			comment := func(value string) Middleware {
+				return func(next Executor) Executor {
+					return func(ctx context.Context, query string, args []any) (Rows, error) {
+						return next(ctx, "/* "+value+" */ "+query, args)
+					}
+				}
+			}
+			db.Use(comment("inner"), comment("outer"))
+			mock.ExpectQuery(`^/\* outer \*/ /\* inner \*/ SELECT 1;$`).WillReturnRows(sqlmock.NewRows(nil))
+			assert.Nil(t, db.Q(`SELECT 1`).Exec())
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"short-circuit with cached rows", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			db.Use(
+				func(next Executor) Executor {
+					return func(ctx context.Context, query string, args []any) (Rows, error) {
+						return &cachedRows{values: []int{1, 2, 3}}, nil
+					}
+				},
+			)
+			ids := make([]int, 0)
+			assert.Nil(t, db.Q(`SELECT id FROM tests`).Exec(&ids))
+			assert.Equal(t, []int{1, 2, 3}, ids)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"read-only", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			db.Use(ReadOnlyMiddleware())
+			assert.ErrorIs(t, db.Q(`DELETE FROM tests`).Exec(), ErrorReadOnly)
+			mock.ExpectQuery(`SELECT 1;`).WillReturnRows(sqlmock.NewRows(nil))
+			assert.Nil(t, db.Q(`SELECT 1`).Exec())
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+}
@@ -1,6 +1,10 @@
 package migrator
 
-import "github.com/creamsensation/quirk"
+import (
+	"context"
+
+	"github.com/creamsensation/quirk"
+)
 
 type Control interface {
 	DB(name ...string) *quirk.Quirk
@@ -8,6 +12,7 @@ type Control interface {
 
 type control struct {
 	*migrator
+	ctx context.Context
 }
 
 func (c *control) DB(name ...string) *quirk.Quirk {
@@ -19,5 +24,5 @@ func (c *control) DB(name ...string) *quirk.Quirk {
 	if !ok {
 		panic(ErrorInvalidDatabase)
 	}
-	return quirk.New(d)
+	return quirk.New(d).Context(c.ctx)
 }
@@ -3,3 +3,13 @@ package migrator
 const (
 	mainDbname = "main"
 )
+
+const (
+	directionUp   = "up"
+	directionDown = "down"
+)
+
+const (
+	attributeMigrationName      = "db.migration.name"
+	attributeMigrationDirection = "db.migration.direction"
+)
@@ -1,6 +1,7 @@
 package migrator
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -123,8 +124,12 @@ func (m *migrator) Up() {
 			continue
 		}
 		fmt.Printf("Up [%s]...\n", item.name)
-		item.up(&control{m})
-		m.insertMigration(item.name)
+		m.step(
+			item.name, directionUp, func(ctx context.Context) {
+				item.up(&control{m, ctx})
+				m.insertMigration(item.name)
+			},
+		)
 	}
 	for _, db := range m.databases {
 		db.MustCommit()
@@ -147,8 +152,12 @@ func (m *migrator) Down() {
 			continue
 		}
 		fmt.Printf("Down [%s]...\n", item.name)
-		item.down(&control{m})
-		m.deleteMigration(item.name)
+		m.step(
+			item.name, directionDown, func(ctx context.Context) {
+				item.down(&control{m, ctx})
+				m.deleteMigration(item.name)
+			},
+		)
 	}
 	for _, db := range m.databases {
 		db.MustCommit()
@@ -161,6 +170,33 @@ func (m *migrator) Down() {
 	}
 }
 
+func (m *migrator) step(name, direction string, fn func(ctx context.Context)) {
+	db, ok := m.databases[mainDbname]
+	if !ok {
+		for _, d := range m.databases {
+			db = d
+			break
+		}
+	}
+	if db == nil {
+		fn(context.Background())
+		return
+	}
+	ctx, span := db.StartSpan(
+		context.Background(), "migration "+direction,
+		quirk.Attribute{Key: attributeMigrationName, Value: name},
+		quirk.Attribute{Key: attributeMigrationDirection, Value: direction},
+	)
+	defer func() {
+		if r := recover(); r != nil {
+			span.End(fmt.Errorf("%v", r))
+			panic(r)
+		}
+		span.End(nil)
+	}()
+	fn(ctx)
+}
+
 func (m *migrator) check(err error) {
 	if err == nil {
 		return
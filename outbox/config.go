@@ -0,0 +1,57 @@
+package outbox
+
+import "time"
+
+type Config interface{}
+
+type config struct {
+	configType int
+	value      any
+}
+
+const (
+	configTypeBatchSize = iota
+	configTypePollInterval
+	configTypeMaxAttempts
+	configTypeDeletePublished
+	configTypeOnError
+)
+
+func WithBatchSize(size int) Config {
+	return config{
+		configType: configTypeBatchSize,
+		value:      size,
+	}
+}
+
+func WithPollInterval(interval time.Duration) Config {
+	return config{
+		configType: configTypePollInterval,
+		value:      interval,
+	}
+}
+
+func WithMaxAttempts(attempts int) Config {
+	return config{
+		configType: configTypeMaxAttempts,
+		value:      attempts,
+	}
+}
+
+func WithDeletePublished(use ...bool) Config {
+	d := true
+	if len(use) > 0 {
+		d = use[0]
+	}
+	return config{
+		configType: configTypeDeletePublished,
+		value:      d,
+	}
+}
+
+func WithOnError(fn func(err error)) Config {
+	return config{
+		configType: configTypeOnError,
+		value:      fn,
+	}
+}
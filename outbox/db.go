@@ -0,0 +1,15 @@
+package outbox
+
+import "time"
+
+const (
+	outboxTable   = "quirk_outbox"
+	notifyChannel = "quirk_outbox"
+	relayLockKey  = "quirk_outbox_relay"
+)
+
+const (
+	DefaultBatchSize    = 100
+	DefaultPollInterval = time.Second
+	DefaultMaxAttempts  = 10
+)
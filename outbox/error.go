@@ -0,0 +1,7 @@
+package outbox
+
+import "errors"
+
+var (
+	ErrorNotInTransaction = errors.New("outbox message must be added inside a session transaction")
+)
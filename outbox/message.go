@@ -0,0 +1,38 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Message struct {
+	Id        int64
+	Topic     string
+	Payload   json.RawMessage
+	Attempts  int
+	CreatedAt time.Time
+}
+
+type messageRow struct {
+	Id        int64
+	Topic     string
+	Payload   string
+	Attempts  int
+	CreatedAt time.Time
+}
+
+func Decode[T any](message Message) (T, error) {
+	var result T
+	err := json.Unmarshal(message.Payload, &result)
+	return result, err
+}
+
+func (r messageRow) message() Message {
+	return Message{
+		Id:        r.Id,
+		Topic:     r.Topic,
+		Payload:   json.RawMessage(r.Payload),
+		Attempts:  r.Attempts,
+		CreatedAt: r.CreatedAt,
+	}
+}
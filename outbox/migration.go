@@ -0,0 +1,30 @@
+package outbox
+
+import (
+	"fmt"
+
+	"github.com/creamsensation/quirk/migrator"
+)
+
+func Up(c migrator.Control) {
+	c.DB().Q(
+		fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %s (
+    id bigserial primary key,
+    topic varchar(255) not null,
+    payload jsonb not null default '{}',
+    attempts int not null default 0,
+    last_error text,
+    published_at timestamptz,
+    created_at timestamptz not null default current_timestamp
+    )`, outboxTable,
+		),
+	).MustExec()
+	c.DB().Q(
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_pending ON %[1]s (id) WHERE published_at IS NULL`, outboxTable),
+	).MustExec()
+}
+
+func Down(c migrator.Control) {
+	c.DB().Q(fmt.Sprintf(`DROP TABLE IF EXISTS %s`, outboxTable)).MustExec()
+}
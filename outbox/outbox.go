@@ -0,0 +1,234 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/creamsensation/quirk"
+)
+
+type Publisher func(ctx context.Context, messages []Message) error
+
+type Relay struct {
+	db              *quirk.DB
+	publisher       Publisher
+	batchSize       int
+	pollInterval    time.Duration
+	maxAttempts     int
+	deletePublished bool
+	onError         func(err error)
+	mu              sync.Mutex
+	stats           Stats
+}
+
+type Stats struct {
+	Batches   int
+	Published int
+	Failed    int
+	Lag       time.Duration
+}
+
+func Add(tx *quirk.DB, topic string, payload any) error {
+	if !tx.InSession() || !tx.InTransaction() {
+		return ErrorNotInTransaction
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	err = tx.Q(
+		fmt.Sprintf(`INSERT INTO %s (topic, payload) VALUES (@topic, CAST(@payload AS jsonb))`, outboxTable),
+		quirk.Map{"topic": topic, "payload": string(b)},
+	).Exec()
+	if err != nil {
+		return err
+	}
+	return tx.Notify(notifyChannel, topic)
+}
+
+func NewRelay(db *quirk.DB, publisher Publisher, configs ...Config) *Relay {
+	r := &Relay{
+		db:           db,
+		publisher:    publisher,
+		batchSize:    DefaultBatchSize,
+		pollInterval: DefaultPollInterval,
+		maxAttempts:  DefaultMaxAttempts,
+	}
+	for _, item := range configs {
+		c, ok := item.(config)
+		if !ok {
+			continue
+		}
+		switch c.configType {
+		case configTypeBatchSize:
+			if v, ok := c.value.(int); ok && v > 0 {
+				r.batchSize = v
+			}
+		case configTypePollInterval:
+			if v, ok := c.value.(time.Duration); ok && v > 0 {
+				r.pollInterval = v
+			}
+		case configTypeMaxAttempts:
+			if v, ok := c.value.(int); ok && v > 0 {
+				r.maxAttempts = v
+			}
+		case configTypeDeletePublished:
+			r.deletePublished, _ = c.value.(bool)
+		case configTypeOnError:
+			r.onError, _ = c.value.(func(err error))
+		}
+	}
+	return r
+}
+
+func (r *Relay) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+func (r *Relay) Run(ctx context.Context) {
+	wake := make(chan struct{}, 1)
+	go r.listen(ctx, wake)
+	for ctx.Err() == nil {
+		n, err := r.Process(ctx)
+		if err != nil {
+			r.reportError(ctx, err)
+		}
+		if err == nil && n == r.batchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-wake:
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+func (r *Relay) Process(ctx context.Context) (int, error) {
+	t := time.Now()
+	messages := make([]Message, 0)
+	err := r.db.Session(
+		ctx, func(s *quirk.DB) error {
+			tx, err := s.Begin()
+			if err != nil {
+				return err
+			}
+			var publishErr error
+			acquired, err := tx.TryAdvisoryXactLock(
+				ctx, relayLockKey, func(tx *quirk.DB) error {
+					var fetchErr error
+					if messages, fetchErr = r.fetch(ctx, tx); fetchErr != nil || len(messages) == 0 {
+						return fetchErr
+					}
+					if publishErr = r.publish(ctx, messages); publishErr != nil {
+						return r.markFailed(ctx, tx, messages, publishErr)
+					}
+					return r.markPublished(ctx, tx, messages)
+				},
+			)
+			if err != nil || !acquired || len(messages) == 0 {
+				return errors.Join(err, tx.Rollback())
+			}
+			return errors.Join(publishErr, tx.Commit())
+		},
+	)
+	if len(messages) > 0 {
+		r.record(t, messages, err)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(messages), nil
+}
+
+func (r *Relay) listen(ctx context.Context, wake chan<- struct{}) {
+	notifications, err := r.db.Listen(ctx, notifyChannel)
+	if err != nil {
+		r.reportError(ctx, err)
+		return
+	}
+	for range notifications {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (r *Relay) fetch(ctx context.Context, tx *quirk.DB) ([]Message, error) {
+	rows := make([]messageRow, 0)
+	err := tx.Q(
+		fmt.Sprintf(
+			`SELECT id, topic, payload, attempts, created_at
+FROM %s
+WHERE attempts < @max_attempts AND published_at IS NULL
+ORDER BY id
+LIMIT @limit FOR UPDATE`, outboxTable,
+		),
+		quirk.Map{"max_attempts": r.maxAttempts, "limit": r.batchSize},
+	).Context(ctx).Exec(&rows)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]Message, len(rows))
+	for i, row := range rows {
+		messages[i] = row.message()
+	}
+	return messages, nil
+}
+
+func (r *Relay) publish(ctx context.Context, messages []Message) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("%v", rec)
+		}
+	}()
+	return r.publisher(ctx, messages)
+}
+
+func (r *Relay) markPublished(ctx context.Context, tx *quirk.DB, messages []Message) error {
+	query := `UPDATE %s SET published_at = current_timestamp WHERE id = ANY(@ids)`
+	if r.deletePublished {
+		query = `DELETE FROM %s WHERE id = ANY(@ids)`
+	}
+	return tx.Q(fmt.Sprintf(query, outboxTable), quirk.Map{"ids": messageIds(messages)}).Context(ctx).Exec()
+}
+
+func (r *Relay) markFailed(ctx context.Context, tx *quirk.DB, messages []Message, err error) error {
+	return tx.Q(
+		fmt.Sprintf(`UPDATE %s SET attempts = attempts + 1, last_error = @error WHERE id = ANY(@ids)`, outboxTable),
+		quirk.Map{"ids": messageIds(messages), "error": err.Error()},
+	).Context(ctx).Exec()
+}
+
+func (r *Relay) record(t time.Time, messages []Message, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Batches++
+	r.stats.Lag = t.Sub(messages[0].CreatedAt)
+	if err != nil {
+		r.stats.Failed += len(messages)
+	} else {
+		r.stats.Published += len(messages)
+	}
+}
+
+func (r *Relay) reportError(ctx context.Context, err error) {
+	if r.onError != nil && ctx.Err() == nil {
+		r.onError(err)
+	}
+}
+
+func messageIds(messages []Message) []int64 {
+	ids := make([]int64, len(messages))
+	for i, m := range messages {
+		ids[i] = m.Id
+	}
+	return ids
+}
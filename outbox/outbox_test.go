@@ -0,0 +1,210 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/creamsensation/quirk"
+	"github.com/stretchr/testify/assert"
+)
+
+type orderCreated struct {
+	Id int `json:"id"`
+}
+
+func TestOutbox(t *testing.T) {
+	createDB := func(t *testing.T) (*quirk.DB, sqlmock.Sqlmock) {
+		dsn := "outbox_" + t.Name()
+		_, mock, err := sqlmock.NewWithDSN(dsn)
+		assert.Nil(t, err)
+		db, err := quirk.Open("sqlmock", dsn)
+		assert.Nil(t, err)
+		return db, mock
+	}
+	lockKey := quirk.AdvisoryLockKey(relayLockKey)
+	messageColumns := []string{"id", "topic", "payload", "attempts", "created_at"}
+	expectBatch := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`BEGIN;`).WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock\(\$1\);`).
+			WithArgs(lockKey).
+			WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+		mock.ExpectQuery(
+			regexp.QuoteMeta(
+				`SELECT id, topic, payload, attempts, created_at
+FROM quirk_outbox
+WHERE attempts < $1 AND published_at IS NULL
+ORDER BY id
+LIMIT $2 FOR UPDATE;`,
+			),
+		).
+			WithArgs(DefaultMaxAttempts, 2).
+			WillReturnRows(
+				sqlmock.NewRows(messageColumns).
+					AddRow(1, "orders", `{"id":1}`, 0, time.Now().Add(-time.Minute)).
+					AddRow(2, "orders", `{"id":2}`, 0, time.Now()),
+			)
+	}
+	t.Run(
+		"add requires session transaction", func(t *testing.T) {
+			db, mock := createDB(t)
+			assert.ErrorIs(t, Add(db, "orders", orderCreated{Id: 1}), ErrorNotInTransaction)
+			mock.ExpectQuery(`BEGIN;`).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(`ROLLBACK;`).WillReturnRows(sqlmock.NewRows(nil))
+			tx := db.MustBegin()
+			assert.True(t, tx.InTransaction())
+			assert.False(t, tx.InSession())
+			assert.ErrorIs(t, Add(tx, "orders", orderCreated{Id: 1}), ErrorNotInTransaction)
+			tx.MustRollback()
+			mock.ExpectQuery(`BEGIN;`).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO quirk_outbox (topic, payload)`)).
+				WithArgs("orders", `{"id":1}`).
+				WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(`SELECT pg_notify`).WithArgs(notifyChannel, "orders").WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(`COMMIT;`).WillReturnRows(sqlmock.NewRows(nil))
+			assert.Nil(
+				t, db.Session(
+					context.Background(), func(s *quirk.DB) error {
+						tx := s.MustBegin()
+						assert.True(t, tx.InTransaction())
+						if err := Add(tx, "orders", orderCreated{Id: 1}); err != nil {
+							return err
+						}
+						return tx.Commit()
+					},
+				),
+			)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"relay publishes batch", func(t *testing.T) {
+			db, mock := createDB(t)
+			published := make([]orderCreated, 0)
+			relay := NewRelay(
+				db, func(ctx context.Context, messages []Message) error {
+					for _, m := range messages {
+						order, err := Decode[orderCreated](m)
+						assert.Nil(t, err)
+						published = append(published, order)
+					}
+					return nil
+				},
+				WithBatchSize(2),
+			)
+			expectBatch(mock)
+			mock.ExpectQuery(regexp.QuoteMeta(`UPDATE quirk_outbox SET published_at = current_timestamp WHERE id = ANY($1);`)).
+				WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(`COMMIT;`).WillReturnRows(sqlmock.NewRows(nil))
+			n, err := relay.Process(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, 2, n)
+			assert.Equal(t, []orderCreated{{Id: 1}, {Id: 2}}, published)
+			stats := relay.Stats()
+			assert.Equal(t, 1, stats.Batches)
+			assert.Equal(t, 2, stats.Published)
+			assert.Equal(t, 0, stats.Failed)
+			assert.GreaterOrEqual(t, stats.Lag, time.Minute)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"failed publish keeps messages pending", func(t *testing.T) {
+			db, mock := createDB(t)
+			failure := errors.New("broker unavailable")
+			relay := NewRelay(
+				db, func(ctx context.Context, messages []Message) error {
+					return failure
+				},
+				WithBatchSize(2), WithDeletePublished(),
+			)
+			expectBatch(mock)
+			mock.ExpectQuery(regexp.QuoteMeta(`UPDATE quirk_outbox SET attempts = attempts + 1`)).
+				WithArgs("broker unavailable", sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(`COMMIT;`).WillReturnRows(sqlmock.NewRows(nil))
+			n, err := relay.Process(context.Background())
+			assert.ErrorIs(t, err, failure)
+			assert.Equal(t, 0, n)
+			stats := relay.Stats()
+			assert.Equal(t, 1, stats.Batches)
+			assert.Equal(t, 0, stats.Published)
+			assert.Equal(t, 2, stats.Failed)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"exhausted messages skipped", func(t *testing.T) {
+			db, mock := createDB(t)
+			relay := NewRelay(
+				db, func(ctx context.Context, messages []Message) error {
+					return nil
+				},
+				WithMaxAttempts(3),
+			)
+			mock.ExpectQuery(`BEGIN;`).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock\(\$1\);`).
+				WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+			mock.ExpectQuery(regexp.QuoteMeta(`WHERE attempts < $1 AND published_at IS NULL`)).
+				WithArgs(3, DefaultBatchSize).
+				WillReturnRows(sqlmock.NewRows(messageColumns))
+			mock.ExpectQuery(`ROLLBACK;`).WillReturnRows(sqlmock.NewRows(nil))
+			n, err := relay.Process(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, 0, n)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"relay errors reported", func(t *testing.T) {
+			db, mock := createDB(t)
+			failure := errors.New("connection refused")
+			errs := make(chan error, 2)
+			relay := NewRelay(
+				db, func(ctx context.Context, messages []Message) error {
+					return nil
+				},
+				WithPollInterval(time.Hour), WithOnError(
+					func(err error) {
+						errs <- err
+					},
+				),
+			)
+			mock.ExpectQuery(`BEGIN;`).WillReturnError(failure)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				relay.Run(ctx)
+				close(done)
+			}()
+			reported := []error{<-errs, <-errs}
+			cancel()
+			<-done
+			assert.True(t, errors.Is(reported[0], failure) || errors.Is(reported[1], failure))
+			assert.True(t, errors.Is(reported[0], quirk.ErrorInvalidConfig) || errors.Is(reported[1], quirk.ErrorInvalidConfig))
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"relay lock held elsewhere", func(t *testing.T) {
+			db, mock := createDB(t)
+			relay := NewRelay(
+				db, func(ctx context.Context, messages []Message) error {
+					t.Fatal("must not publish without lock")
+					return nil
+				},
+			)
+			mock.ExpectQuery(`BEGIN;`).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock\(\$1\);`).
+				WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(false))
+			mock.ExpectQuery(`ROLLBACK;`).WillReturnRows(sqlmock.NewRows(nil))
+			n, err := relay.Process(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, 0, n)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+}
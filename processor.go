@@ -84,6 +84,9 @@ func processQueryParts(q *Quirk) (string, []any, error) {
 			if isMap {
 				partArg.value = transformMapToJsonb(partArg.value)
 			}
+			if q.DB != nil && slices.Contains(q.redacted, strings.ToLower(partArg.name)) {
+				partArg.value = redactedArg{partArg.value}
+			}
 			if !isSafe {
 				args = append(args, partArg.value)
 			}
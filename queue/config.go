@@ -0,0 +1,99 @@
+package queue
+
+import (
+	"time"
+)
+
+type Config interface{}
+
+type config struct {
+	configType int
+	value      any
+}
+
+type Backoff func(attempt int) time.Duration
+
+const (
+	configTypeConcurrency = iota
+	configTypePollInterval
+	configTypeBackoff
+	configTypeMaxAttempts
+	configTypePriority
+	configTypeRunAt
+	configTypeDelay
+	configTypeUniqueKey
+	configTypeOnError
+)
+
+func WithConcurrency(concurrency int) Config {
+	return config{
+		configType: configTypeConcurrency,
+		value:      concurrency,
+	}
+}
+
+func WithPollInterval(interval time.Duration) Config {
+	return config{
+		configType: configTypePollInterval,
+		value:      interval,
+	}
+}
+
+func WithBackoff(backoff func(attempt int) time.Duration) Config {
+	return config{
+		configType: configTypeBackoff,
+		value:      Backoff(backoff),
+	}
+}
+
+func WithMaxAttempts(attempts int) Config {
+	return config{
+		configType: configTypeMaxAttempts,
+		value:      attempts,
+	}
+}
+
+func WithPriority(priority int) Config {
+	return config{
+		configType: configTypePriority,
+		value:      priority,
+	}
+}
+
+func WithRunAt(runAt time.Time) Config {
+	return config{
+		configType: configTypeRunAt,
+		value:      runAt,
+	}
+}
+
+func WithDelay(delay time.Duration) Config {
+	return config{
+		configType: configTypeDelay,
+		value:      delay,
+	}
+}
+
+func WithUniqueKey(key string) Config {
+	return config{
+		configType: configTypeUniqueKey,
+		value:      key,
+	}
+}
+
+func WithOnError(fn func(err error)) Config {
+	return config{
+		configType: configTypeOnError,
+		value:      fn,
+	}
+}
+
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		return min(delay, max)
+	}
+}
@@ -0,0 +1,22 @@
+package queue
+
+import "time"
+
+const (
+	jobsTable     = "quirk_jobs"
+	notifyChannel = "quirk_jobs"
+	jobSavepoint  = "quirk_job"
+)
+
+const (
+	StatusPending = "pending"
+	StatusDead    = "dead"
+)
+
+const (
+	DefaultConcurrency  = 1
+	DefaultPollInterval = 5 * time.Second
+	DefaultMaxAttempts  = 25
+	defaultBackoffBase  = time.Second
+	defaultBackoffMax   = time.Hour
+)
@@ -0,0 +1,9 @@
+package queue
+
+import "errors"
+
+var (
+	ErrorDuplicateJob  = errors.New("job with unique key already pending")
+	ErrorUnknownJob    = errors.New("no handler registered for job kind")
+	ErrorInvalidConfig = errors.New("invalid queue config")
+)
@@ -0,0 +1,58 @@
+package queue
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/creamsensation/quirk"
+)
+
+type Job struct {
+	Id          int64
+	Queue       string
+	Kind        string
+	Payload     json.RawMessage
+	Priority    int
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	CreatedAt   time.Time
+	tx          *quirk.DB
+}
+
+type jobRow struct {
+	Id          int64
+	Queue       string
+	Kind        string
+	Payload     string
+	Priority    int
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	CreatedAt   time.Time
+}
+
+func (j Job) Tx() *quirk.DB {
+	return j.tx
+}
+
+func Decode[T any](job Job) (T, error) {
+	var result T
+	err := json.Unmarshal(job.Payload, &result)
+	return result, err
+}
+
+func (r jobRow) job(tx *quirk.DB) Job {
+	return Job{
+		Id:          r.Id,
+		Queue:       r.Queue,
+		Kind:        r.Kind,
+		Payload:     json.RawMessage(r.Payload),
+		Priority:    r.Priority,
+		Attempts:    r.Attempts,
+		MaxAttempts: r.MaxAttempts,
+		RunAt:       r.RunAt,
+		CreatedAt:   r.CreatedAt,
+		tx:          tx,
+	}
+}
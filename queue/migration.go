@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"fmt"
+
+	"github.com/creamsensation/quirk/migrator"
+)
+
+func Up(c migrator.Control) {
+	c.DB().Q(
+		fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %[1]s (
+    id bigserial primary key,
+    queue varchar(255) not null,
+    kind varchar(255) not null,
+    payload jsonb not null default '{}',
+    status varchar(32) not null default '%[2]s',
+    priority int not null default 0,
+    attempts int not null default 0,
+    max_attempts int not null default %[3]d,
+    unique_key varchar(255),
+    last_error text,
+    run_at timestamptz not null default current_timestamp,
+    created_at timestamptz not null default current_timestamp,
+    updated_at timestamptz not null default current_timestamp
+    )`, jobsTable, StatusPending, DefaultMaxAttempts,
+		),
+	).MustExec()
+	c.DB().Q(
+		fmt.Sprintf(
+			`CREATE UNIQUE INDEX IF NOT EXISTS %[1]s_unique_key ON %[1]s (queue, unique_key) WHERE unique_key IS NOT NULL AND status = '%[2]s'`,
+			jobsTable, StatusPending,
+		),
+	).MustExec()
+	c.DB().Q(
+		fmt.Sprintf(
+			`CREATE INDEX IF NOT EXISTS %[1]s_fetch ON %[1]s (queue, priority DESC, run_at) WHERE status = '%[2]s'`,
+			jobsTable, StatusPending,
+		),
+	).MustExec()
+}
+
+func Down(c migrator.Control) {
+	c.DB().Q(fmt.Sprintf(`DROP TABLE IF EXISTS %s`, jobsTable)).MustExec()
+}
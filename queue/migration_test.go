@@ -0,0 +1,73 @@
+package queue
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/creamsensation/quirk"
+	"github.com/stretchr/testify/assert"
+)
+
+type control struct {
+	db *quirk.DB
+}
+
+func (c control) DB(name ...string) *quirk.Quirk {
+	return quirk.New(c.db)
+}
+
+func TestMigration(t *testing.T) {
+	createControl := func(t *testing.T) (control, sqlmock.Sqlmock) {
+		dsn := "queue_" + t.Name()
+		_, mock, err := sqlmock.NewWithDSN(dsn)
+		assert.Nil(t, err)
+		db, err := quirk.Open("sqlmock", dsn)
+		assert.Nil(t, err)
+		return control{db: db}, mock
+	}
+	t.Run(
+		"up", func(t *testing.T) {
+			c, mock := createControl(t)
+			mock.ExpectQuery(
+				regexp.QuoteMeta(
+					`CREATE TABLE IF NOT EXISTS quirk_jobs (
+    id bigserial primary key,
+    queue varchar(255) not null,
+    kind varchar(255) not null,
+    payload jsonb not null default '{}',
+    status varchar(32) not null default 'pending',
+    priority int not null default 0,
+    attempts int not null default 0,
+    max_attempts int not null default 25,
+    unique_key varchar(255),
+    last_error text,
+    run_at timestamptz not null default current_timestamp,
+    created_at timestamptz not null default current_timestamp,
+    updated_at timestamptz not null default current_timestamp
+    );`,
+				),
+			).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(
+				regexp.QuoteMeta(
+					`CREATE UNIQUE INDEX IF NOT EXISTS quirk_jobs_unique_key ON quirk_jobs (queue, unique_key) WHERE unique_key IS NOT NULL AND status = 'pending';`,
+				),
+			).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(
+				regexp.QuoteMeta(
+					`CREATE INDEX IF NOT EXISTS quirk_jobs_fetch ON quirk_jobs (queue, priority DESC, run_at) WHERE status = 'pending';`,
+				),
+			).WillReturnRows(sqlmock.NewRows(nil))
+			Up(c)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"down", func(t *testing.T) {
+			c, mock := createControl(t)
+			mock.ExpectQuery(regexp.QuoteMeta(`DROP TABLE IF EXISTS quirk_jobs;`)).WillReturnRows(sqlmock.NewRows(nil))
+			Down(c)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+}
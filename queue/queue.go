@@ -0,0 +1,309 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/creamsensation/quirk"
+)
+
+type Queue struct {
+	db           *quirk.DB
+	name         string
+	concurrency  int
+	pollInterval time.Duration
+	backoff      Backoff
+	maxAttempts  int
+	onError      func(err error)
+	mu           sync.RWMutex
+	handlers     map[string]func(ctx context.Context, job Job) error
+}
+
+type enqueueOptions struct {
+	priority    int
+	maxAttempts int
+	runAt       sql.NullTime
+	delay       time.Duration
+	uniqueKey   sql.NullString
+}
+
+func New(db *quirk.DB, name string, configs ...Config) *Queue {
+	q := &Queue{
+		db:           db,
+		name:         name,
+		concurrency:  DefaultConcurrency,
+		pollInterval: DefaultPollInterval,
+		backoff:      ExponentialBackoff(defaultBackoffBase, defaultBackoffMax),
+		maxAttempts:  DefaultMaxAttempts,
+		handlers:     make(map[string]func(ctx context.Context, job Job) error),
+	}
+	for _, item := range configs {
+		c, ok := item.(config)
+		if !ok {
+			continue
+		}
+		switch c.configType {
+		case configTypeConcurrency:
+			if v, ok := c.value.(int); ok && v > 0 {
+				q.concurrency = v
+			}
+		case configTypePollInterval:
+			if v, ok := c.value.(time.Duration); ok && v > 0 {
+				q.pollInterval = v
+			}
+		case configTypeBackoff:
+			if v, ok := c.value.(Backoff); ok && v != nil {
+				q.backoff = v
+			}
+		case configTypeMaxAttempts:
+			if v, ok := c.value.(int); ok && v > 0 {
+				q.maxAttempts = v
+			}
+		case configTypeOnError:
+			q.onError, _ = c.value.(func(err error))
+		}
+	}
+	return q
+}
+
+func Handle[T any](q *Queue, kind string, fn func(ctx context.Context, job Job, payload T) error) {
+	q.Handle(
+		kind, func(ctx context.Context, job Job) error {
+			payload, err := Decode[T](job)
+			if err != nil {
+				return err
+			}
+			return fn(ctx, job, payload)
+		},
+	)
+}
+
+func (q *Queue) Handle(kind string, fn func(ctx context.Context, job Job) error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = fn
+}
+
+func (q *Queue) Name() string {
+	return q.name
+}
+
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any, configs ...Config) (int64, error) {
+	options, err := q.createEnqueueOptions(configs...)
+	if err != nil {
+		return 0, err
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]int64, 0)
+	err = q.db.Q(
+		fmt.Sprintf(
+			`INSERT INTO %[1]s (queue, kind, payload, priority, max_attempts, unique_key, run_at)
+VALUES (@queue, @kind, CAST(@payload AS jsonb), @priority, @max_attempts, @unique_key, COALESCE(@run_at, current_timestamp) + @delay * interval '1 millisecond')
+ON CONFLICT (queue, unique_key) WHERE unique_key IS NOT NULL AND status = '%[2]s' DO NOTHING
+RETURNING id`, jobsTable, StatusPending,
+		),
+		quirk.Map{
+			"queue":        q.name,
+			"kind":         kind,
+			"payload":      string(b),
+			"priority":     options.priority,
+			"max_attempts": options.maxAttempts,
+			"unique_key":   options.uniqueKey,
+			"run_at":       options.runAt,
+			"delay":        options.delay.Milliseconds(),
+		},
+	).Context(ctx).Exec(&ids)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, ErrorDuplicateJob
+	}
+	if err := q.db.Notify(notifyChannel, q.name); err != nil {
+		return ids[0], err
+	}
+	return ids[0], nil
+}
+
+func (q *Queue) Work(ctx context.Context) {
+	wake := make(chan struct{}, q.concurrency)
+	go q.listen(ctx, wake)
+	var wg sync.WaitGroup
+	for i := 0; i < q.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx, wake)
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *Queue) Process(ctx context.Context) (found bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Join(err, fmt.Errorf("%v", r))
+		}
+	}()
+	err = q.db.Session(
+		ctx, func(s *quirk.DB) error {
+			tx, err := s.Begin()
+			if err != nil {
+				return err
+			}
+			job, ok, err := q.claim(ctx, tx)
+			if err != nil || !ok {
+				return errors.Join(err, tx.Rollback())
+			}
+			found = true
+			if err := q.complete(ctx, tx, job, q.run(ctx, job)); err != nil {
+				return errors.Join(err, tx.Rollback())
+			}
+			return tx.Commit()
+		},
+	)
+	return found, err
+}
+
+func (q *Queue) listen(ctx context.Context, wake chan<- struct{}) {
+	notifications, err := q.db.Listen(ctx, notifyChannel)
+	if err != nil {
+		q.reportError(ctx, err)
+		return
+	}
+	for n := range notifications {
+		if !n.Reconnected && n.Payload != q.name {
+			continue
+		}
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (q *Queue) work(ctx context.Context, wake <-chan struct{}) {
+	for ctx.Err() == nil {
+		found, err := q.Process(ctx)
+		if err != nil {
+			q.reportError(ctx, err)
+		}
+		if found && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-wake:
+		case <-time.After(q.pollInterval):
+		}
+	}
+}
+
+func (q *Queue) reportError(ctx context.Context, err error) {
+	if q.onError != nil && ctx.Err() == nil {
+		q.onError(err)
+	}
+}
+
+func (q *Queue) claim(ctx context.Context, tx *quirk.DB) (Job, bool, error) {
+	rows := make([]jobRow, 0)
+	err := tx.Q(
+		fmt.Sprintf(
+			`SELECT id, queue, kind, payload, priority, attempts, max_attempts, run_at, created_at
+FROM %s
+WHERE queue = @queue AND status = '%s' AND run_at <= current_timestamp
+ORDER BY priority DESC, run_at, id
+LIMIT 1
+FOR UPDATE SKIP LOCKED`, jobsTable, StatusPending,
+		),
+		quirk.Map{"queue": q.name},
+	).Context(ctx).Exec(&rows)
+	if err != nil || len(rows) == 0 {
+		return Job{}, false, err
+	}
+	return rows[0].job(tx), true, nil
+}
+
+func (q *Queue) run(ctx context.Context, job Job) (err error) {
+	q.mu.RLock()
+	handler, ok := q.handlers[job.Kind]
+	q.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrorUnknownJob, job.Kind)
+	}
+	if err := job.tx.Q(fmt.Sprintf(`SAVEPOINT %s`, jobSavepoint)).Context(ctx).Exec(); err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+		if err != nil {
+			err = errors.Join(err, job.tx.Q(fmt.Sprintf(`ROLLBACK TO SAVEPOINT %s`, jobSavepoint)).Context(ctx).Exec())
+		}
+	}()
+	return handler(ctx, job)
+}
+
+func (q *Queue) complete(ctx context.Context, tx *quirk.DB, job Job, err error) error {
+	if err == nil {
+		return tx.Q(fmt.Sprintf(`DELETE FROM %s WHERE id = @id`, jobsTable), quirk.Map{"id": job.Id}).Context(ctx).Exec()
+	}
+	attempts := job.Attempts + 1
+	if attempts >= job.MaxAttempts {
+		return tx.Q(
+			fmt.Sprintf(
+				`UPDATE %s SET status = '%s', attempts = @attempts, last_error = @error, updated_at = current_timestamp WHERE id = @id`,
+				jobsTable, StatusDead,
+			),
+			quirk.Map{"id": job.Id, "attempts": attempts, "error": err.Error()},
+		).Context(ctx).Exec()
+	}
+	return tx.Q(
+		fmt.Sprintf(
+			`UPDATE %s SET attempts = @attempts, last_error = @error, run_at = current_timestamp + @delay * interval '1 millisecond', updated_at = current_timestamp WHERE id = @id`,
+			jobsTable,
+		),
+		quirk.Map{"id": job.Id, "attempts": attempts, "error": err.Error(), "delay": q.backoff(attempts).Milliseconds()},
+	).Context(ctx).Exec()
+}
+
+func (q *Queue) createEnqueueOptions(configs ...Config) (enqueueOptions, error) {
+	options := enqueueOptions{maxAttempts: q.maxAttempts}
+	for _, item := range configs {
+		c, ok := item.(config)
+		if !ok {
+			continue
+		}
+		switch c.configType {
+		case configTypePriority:
+			options.priority, _ = c.value.(int)
+		case configTypeMaxAttempts:
+			if v, ok := c.value.(int); ok && v > 0 {
+				options.maxAttempts = v
+			}
+		case configTypeRunAt:
+			if v, ok := c.value.(time.Time); ok {
+				options.runAt = sql.NullTime{Time: v, Valid: true}
+			}
+		case configTypeDelay:
+			options.delay, _ = c.value.(time.Duration)
+		case configTypeUniqueKey:
+			if v, ok := c.value.(string); ok && len(v) > 0 {
+				options.uniqueKey = sql.NullString{String: v, Valid: true}
+			}
+		}
+	}
+	if options.delay < 0 {
+		return options, fmt.Errorf("%w: negative delay", ErrorInvalidConfig)
+	}
+	return options, nil
+}
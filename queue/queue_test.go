@@ -0,0 +1,195 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/creamsensation/quirk"
+	"github.com/stretchr/testify/assert"
+)
+
+type email struct {
+	To string `json:"to"`
+}
+
+func TestQueue(t *testing.T) {
+	createQueue := func(t *testing.T, configs ...Config) (*Queue, sqlmock.Sqlmock) {
+		dsn := "queue_" + t.Name()
+		_, mock, err := sqlmock.NewWithDSN(dsn)
+		assert.Nil(t, err)
+		db, err := quirk.Open("sqlmock", dsn)
+		assert.Nil(t, err)
+		return New(db, "mails", configs...), mock
+	}
+	claimQuery := `SELECT id, queue, kind, payload, priority, attempts, max_attempts, run_at, created_at
+FROM quirk_jobs
+WHERE queue = $1 AND status = 'pending' AND run_at <= current_timestamp
+ORDER BY priority DESC, run_at, id
+LIMIT 1
+FOR UPDATE SKIP LOCKED;`
+	jobColumns := []string{"id", "queue", "kind", "payload", "priority", "attempts", "max_attempts", "run_at", "created_at"}
+	expectClaim := func(mock sqlmock.Sqlmock, attempts, maxAttempts int) {
+		mock.ExpectQuery(`BEGIN;`).WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery(regexp.QuoteMeta(claimQuery)).
+			WithArgs("mails").
+			WillReturnRows(
+				sqlmock.NewRows(jobColumns).
+					AddRow(1, "mails", "welcome", `{"to":"john@doe.com"}`, 0, attempts, maxAttempts, time.Now(), time.Now()),
+			)
+		mock.ExpectQuery(`SAVEPOINT quirk_job;`).WillReturnRows(sqlmock.NewRows(nil))
+	}
+	t.Run(
+		"enqueue", func(t *testing.T) {
+			q, mock := createQueue(t)
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO quirk_jobs`)).
+				WithArgs("mails", "welcome", `{"to":"john@doe.com"}`, 10, DefaultMaxAttempts, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(60000)).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			mock.ExpectQuery(`SELECT pg_notify`).WithArgs(notifyChannel, "mails").WillReturnRows(sqlmock.NewRows(nil))
+			id, err := q.Enqueue(
+				context.Background(), "welcome", email{To: "john@doe.com"},
+				WithPriority(10), WithDelay(time.Minute), WithUniqueKey("john@doe.com"),
+			)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(1), id)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"enqueue notifies primary in cluster", func(t *testing.T) {
+			_, primaryMock, err := sqlmock.NewWithDSN("queue_primary_" + t.Name())
+			assert.Nil(t, err)
+			_, replicaMock, err := sqlmock.NewWithDSN("queue_replica_" + t.Name())
+			assert.Nil(t, err)
+			primary, err := quirk.Open("sqlmock", "queue_primary_"+t.Name())
+			assert.Nil(t, err)
+			replica, err := quirk.Open("sqlmock", "queue_replica_"+t.Name())
+			assert.Nil(t, err)
+			q := New(quirk.NewCluster(primary, replica), "mails")
+			primaryMock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO quirk_jobs`)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			primaryMock.ExpectQuery(`SELECT pg_notify`).WithArgs(notifyChannel, "mails").WillReturnRows(sqlmock.NewRows(nil))
+			_, err = q.Enqueue(context.Background(), "welcome", email{})
+			assert.Nil(t, err)
+			assert.Nil(t, primaryMock.ExpectationsWereMet())
+			assert.Nil(t, replicaMock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"duplicate unique key", func(t *testing.T) {
+			q, mock := createQueue(t)
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO quirk_jobs`)).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			_, err := q.Enqueue(context.Background(), "welcome", email{}, WithUniqueKey("john@doe.com"))
+			assert.ErrorIs(t, err, ErrorDuplicateJob)
+		},
+	)
+	t.Run(
+		"process completed job", func(t *testing.T) {
+			q, mock := createQueue(t)
+			var received email
+			Handle(
+				q, "welcome", func(ctx context.Context, job Job, payload email) error {
+					assert.NotNil(t, job.Tx())
+					received = payload
+					return nil
+				},
+			)
+			expectClaim(mock, 0, DefaultMaxAttempts)
+			mock.ExpectQuery(`DELETE FROM quirk_jobs WHERE id = \$1;`).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(`COMMIT;`).WillReturnRows(sqlmock.NewRows(nil))
+			found, err := q.Process(context.Background())
+			assert.Nil(t, err)
+			assert.True(t, found)
+			assert.Equal(t, "john@doe.com", received.To)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"failed job retried with backoff", func(t *testing.T) {
+			q, mock := createQueue(t, WithBackoff(func(attempt int) time.Duration { return time.Duration(attempt) * time.Second }))
+			q.Handle(
+				"welcome", func(ctx context.Context, job Job) error {
+					return errors.New("smtp unavailable")
+				},
+			)
+			expectClaim(mock, 1, DefaultMaxAttempts)
+			mock.ExpectQuery(`ROLLBACK TO SAVEPOINT quirk_job;`).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(regexp.QuoteMeta(`UPDATE quirk_jobs SET attempts`)).
+				WithArgs(2, "smtp unavailable", int64(2000), int64(1)).
+				WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(`COMMIT;`).WillReturnRows(sqlmock.NewRows(nil))
+			found, err := q.Process(context.Background())
+			assert.Nil(t, err)
+			assert.True(t, found)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"exhausted job moved to dead letter", func(t *testing.T) {
+			q, mock := createQueue(t)
+			q.Handle(
+				"welcome", func(ctx context.Context, job Job) error {
+					panic("boom")
+				},
+			)
+			expectClaim(mock, 2, 3)
+			mock.ExpectQuery(`ROLLBACK TO SAVEPOINT quirk_job;`).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(regexp.QuoteMeta(`UPDATE quirk_jobs SET status = 'dead'`)).
+				WithArgs(3, "boom", int64(1)).
+				WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(`COMMIT;`).WillReturnRows(sqlmock.NewRows(nil))
+			found, err := q.Process(context.Background())
+			assert.Nil(t, err)
+			assert.True(t, found)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"empty queue", func(t *testing.T) {
+			q, mock := createQueue(t)
+			mock.ExpectQuery(`BEGIN;`).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(regexp.QuoteMeta(claimQuery)).WillReturnRows(sqlmock.NewRows(jobColumns))
+			mock.ExpectQuery(`ROLLBACK;`).WillReturnRows(sqlmock.NewRows(nil))
+			found, err := q.Process(context.Background())
+			assert.Nil(t, err)
+			assert.False(t, found)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"worker errors reported", func(t *testing.T) {
+			failure := errors.New("connection refused")
+			errs := make(chan error, 2)
+			q, mock := createQueue(
+				t, WithConcurrency(1), WithPollInterval(time.Hour), WithOnError(
+					func(err error) {
+						errs <- err
+					},
+				),
+			)
+			mock.ExpectQuery(`BEGIN;`).WillReturnError(failure)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				q.Work(ctx)
+				close(done)
+			}()
+			reported := []error{<-errs, <-errs}
+			cancel()
+			<-done
+			assert.True(t, errors.Is(reported[0], failure) || errors.Is(reported[1], failure))
+			assert.True(t, errors.Is(reported[0], quirk.ErrorInvalidConfig) || errors.Is(reported[1], quirk.ErrorInvalidConfig))
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"exponential backoff", func(t *testing.T) {
+			backoff := ExponentialBackoff(time.Second, 5*time.Second)
+			assert.Equal(t, time.Second, backoff(1))
+			assert.Equal(t, 4*time.Second, backoff(3))
+			assert.Equal(t, 5*time.Second, backoff(10))
+		},
+	)
+}
@@ -1,7 +1,7 @@
 package quirk
 
 import (
-	"database/sql"
+	"context"
 	"reflect"
 	"regexp"
 	"strings"
@@ -15,9 +15,11 @@ type Quirk struct {
 	*DB
 	driverName    string
 	dbname        string
+	ctx           context.Context
+	timeout       time.Duration
+	primary       bool
 	parts         []queryPart
-	rows          *sql.Rows
-	subscriptions []subscription
+	subscriptions []Subscription
 }
 
 type Safe []byte
@@ -41,7 +43,8 @@ func New(db *DB) *Quirk {
 	q := &Quirk{
 		DB:            db,
 		driverName:    db.driverName,
-		subscriptions: make([]subscription, 0),
+		ctx:           context.Background(),
+		subscriptions: make([]Subscription, 0),
 	}
 	return q
 }
@@ -55,6 +58,11 @@ func (q *Quirk) Q(query string, arg ...Map) *Quirk {
 	return q
 }
 
+func (q *Quirk) Context(ctx context.Context) *Quirk {
+	q.ctx = ctx
+	return q
+}
+
 func (q *Quirk) WhereExists() bool {
 	n := len(q.parts)
 	if n > 0 {
@@ -83,7 +91,7 @@ func (q *Quirk) If(condition bool, query string, arg ...Map) *Quirk {
 	return q
 }
 
-func (q *Quirk) Subscribe(s subscription) {
+func (q *Quirk) Subscribe(s Subscription) {
 	q.subscriptions = append(q.subscriptions, s)
 }
 
@@ -121,46 +129,64 @@ func (q *Quirk) exec(result ...any) error {
 	if !strings.HasSuffix(mergedQueryParts, querySuffix) {
 		mergedQueryParts += querySuffix
 	}
-	rows, err := q.DB.Query(mergedQueryParts, args...)
+	if q.comment {
+		if comment := createComment(q.ctx); len(comment) > 0 {
+			mergedQueryParts = comment + " " + mergedQueryParts
+		}
+	}
+	ctx, span := q.startQuerySpan(q.ctx, mergedQueryParts)
+	ctx, cancel, err := q.applyTimeout(ctx)
+	defer cancel()
+	rowsCount := 0
+	target := q.querier()
+	if err == nil {
+		rowsCount, err = q.query(ctx, mergedQueryParts, args, &target, result...)
+		err = wrapTimeoutError(ctx, err)
+	}
+	q.afterQuery(t, target, mergedQueryParts, args, rowsCount, err)
+	span.SetAttributes(Attribute{Key: AttributeDbRows, Value: rowsCount})
+	span.End(err)
+	return err
+}
+
+func (q *Quirk) query(ctx context.Context, query string, args []any, target *querier, result ...any) (int, error) {
+	rows, err := q.executor(q.primary, target)(ctx, query, args)
 	if err != nil {
-		q.afterQuery(t, mergedQueryParts, args)
-		return err
+		return 0, err
 	}
 	defer func() {
 		_ = rows.Close()
 	}()
 	if len(result) == 0 {
-		q.afterQuery(t, mergedQueryParts, args)
-		return nil
+		return 0, nil
 	}
 	columns, err := rows.Columns()
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if len(columns) == 0 {
-		q.afterQuery(t, mergedQueryParts, args)
-		return nil
+		return 0, nil
 	}
 	if len(result) > 1 {
-		q.scanMultiple(rows, result...)
-	}
-	if len(result) == 1 {
-		q.scanSingle(rows, columns, result[0])
+		return q.scanMultiple(rows, result...), nil
 	}
-	q.afterQuery(t, mergedQueryParts, args)
-	return nil
+	return q.scanSingle(rows, columns, result[0]), nil
 }
 
-func (q *Quirk) afterQuery(t time.Time, query string, args []any) {
-	queryLog := createQueryLog(q.driverName, query, args...)
-	duration := time.Now().Sub(t)
-	for _, sub := range q.subscriptions {
-		sub(queryLog, duration)
+func (q *Quirk) afterQuery(t time.Time, target querier, query string, args []any, rows int, err error) {
+	if !q.hasQueryConsumers(q.ctx, q.subscriptions...) {
+		return
+	}
+	event := q.createQueryEvent(t, query, args, rows, err)
+	if err == nil && q.isSlowQuery(event.Duration) {
+		event.Plan = q.explain(target, query, args)
 	}
-	log(q.log, queryLog, duration)
+	q.publish(event, q.subscriptions...)
+	q.checkQueryBudget(q.ctx, event)
 }
 
-func (q *Quirk) scanSingle(rows *sql.Rows, columns []string, result any) {
+func (q *Quirk) scanSingle(rows Rows, columns []string, result any) int {
+	count := 0
 	res := reflect.ValueOf(result)
 	rv := reflect.ValueOf(result)
 	rt := reflect.TypeOf(result)
@@ -173,6 +199,7 @@ func (q *Quirk) scanSingle(rows *sql.Rows, columns []string, result any) {
 		rvKind = rv.Elem().Type().Kind()
 	}
 	for rows.Next() {
+		count++
 		rowData := make([]any, len(columns))
 		switch rvKind {
 		case reflect.Map:
@@ -241,14 +268,17 @@ func (q *Quirk) scanSingle(rows *sql.Rows, columns []string, result any) {
 			}
 		}
 	}
+	return count
 }
 
-func (q *Quirk) scanMultiple(rows *sql.Rows, result ...any) {
+func (q *Quirk) scanMultiple(rows Rows, result ...any) int {
+	count := 0
 	resultValues := make([]reflect.Value, len(result))
 	for i, r := range result {
 		resultValues[i] = reflect.ValueOf(r)
 	}
 	for rows.Next() {
+		count++
 		rowData := make([]any, 0)
 		for _, rv := range resultValues {
 			rowData = append(rowData, rv.Interface())
@@ -259,4 +289,5 @@ func (q *Quirk) scanMultiple(rows *sql.Rows, result ...any) {
 			}
 		}
 	}
+	return count
 }
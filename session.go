@@ -0,0 +1,70 @@
+package quirk
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"slices"
+)
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+const (
+	postgresDiscardAll = "DISCARD ALL"
+)
+
+func (d *DB) Session(ctx context.Context, fn func(s *DB) error) (err error) {
+	if d.conn != nil {
+		return fn(d)
+	}
+	conn, err := d.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	s := *d
+	s.conn = conn
+	s.subscriptions = slices.Clone(d.subscriptions)
+	s.middlewares = slices.Clone(d.middlewares)
+	defer func() {
+		err = errors.Join(err, s.release())
+	}()
+	return fn(&s)
+}
+
+func (d *DB) InSession() bool {
+	return d.conn != nil
+}
+
+func (d *DB) querier() querier {
+	if d.conn != nil {
+		return d.conn
+	}
+	return d.DB
+}
+
+func (d *DB) release() error {
+	if d.driverName == Postgres {
+		if _, err := d.conn.ExecContext(context.Background(), postgresDiscardAll); err == nil {
+			return d.conn.Close()
+		}
+	}
+	_ = d.conn.Raw(
+		func(any) error {
+			return driver.ErrBadConn
+		},
+	)
+	return nil
+}
+
+func (d *DB) execStatement(ctx context.Context, query string) error {
+	rows, err := d.querier().QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
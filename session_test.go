@@ -0,0 +1,78 @@
+package quirk
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession(t *testing.T) {
+	t.Run(
+		"pinned connection", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			mock.ExpectQuery(`SET search_path TO tenant;`).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(`SELECT id FROM tests;`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			mock.ExpectExec(postgresDiscardAll).WillReturnResult(sqlmock.NewResult(0, 0))
+			assert.Nil(
+				t, db.Session(
+					context.Background(), func(s *DB) error {
+						assert.True(t, s.InSession())
+						assert.Nil(t, s.Q(`SET search_path TO tenant`).Exec())
+						var id int
+						assert.Nil(t, s.Q(`SELECT id FROM tests`).Exec(&id))
+						assert.Equal(t, 1, id)
+						assert.Equal(t, 1, db.Stats().InUse)
+						return nil
+					},
+				),
+			)
+			assert.False(t, db.InSession())
+			assert.Nil(t, mock.ExpectationsWereMet())
+			assert.Equal(t, 0, db.Stats().InUse)
+			assert.Equal(t, 1, db.Stats().Idle)
+		},
+	)
+	t.Run(
+		"transaction in session", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			mock.ExpectQuery(`BEGIN;`).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(`COMMIT;`).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectExec(postgresDiscardAll).WillReturnResult(sqlmock.NewResult(0, 0))
+			assert.Nil(
+				t, db.Session(
+					context.Background(), func(s *DB) error {
+						tx := s.MustBegin()
+						assert.True(t, tx.InSession())
+						return tx.Commit()
+					},
+				),
+			)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"failed reset discards connection", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			mock.ExpectExec(postgresDiscardAll).WillReturnError(errors.New("in failed transaction"))
+			failure := errors.New("failure")
+			assert.ErrorIs(
+				t, db.Session(
+					context.Background(), func(s *DB) error {
+						return failure
+					},
+				), failure,
+			)
+			assert.Nil(t, mock.ExpectationsWereMet())
+			assert.Equal(t, 0, db.Stats().OpenConnections)
+		},
+	)
+}
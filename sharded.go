@@ -0,0 +1,121 @@
+package quirk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+type ShardedDB struct {
+	shards map[string]*DB
+	names  []string
+	router func(key any) string
+}
+
+func NewSharded(shards map[string]*DB, router func(key any) string) (*ShardedDB, error) {
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("%w: no shards", ErrorInvalidShard)
+	}
+	if router == nil {
+		return nil, fmt.Errorf("%w: missing router", ErrorInvalidShard)
+	}
+	names := make([]string, 0, len(shards))
+	for name, db := range shards {
+		if db == nil {
+			return nil, fmt.Errorf("%w: %s has no database", ErrorInvalidShard, name)
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return &ShardedDB{
+		shards: shards,
+		names:  names,
+		router: router,
+	}, nil
+}
+
+func HashRouter(names ...string) func(key any) string {
+	return func(key any) string {
+		if len(names) == 0 {
+			return ""
+		}
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(fmt.Sprintf("%v", key)))
+		return names[h.Sum64()%uint64(len(names))]
+	}
+}
+
+func (s *ShardedDB) For(key any) (*DB, error) {
+	name := s.router(key)
+	db, ok := s.shards[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrorInvalidShard, name)
+	}
+	return db, nil
+}
+
+func (s *ShardedDB) MustFor(key any) *DB {
+	db, err := s.For(key)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+func (s *ShardedDB) Shard(name string) (*DB, bool) {
+	db, ok := s.shards[name]
+	return db, ok
+}
+
+func (s *ShardedDB) Names() []string {
+	return slices.Clone(s.names)
+}
+
+func (s *ShardedDB) FanOut(ctx context.Context, dest any, build func(db *DB) *Quirk) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Slice {
+		return ErrorInvalidDestination
+	}
+	results := make([]reflect.Value, len(s.names))
+	errs := make([]error, len(s.names))
+	var wg sync.WaitGroup
+	for i, name := range s.names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					errs[i] = fmt.Errorf("%s: %v", name, r)
+				}
+			}()
+			result := reflect.New(rv.Elem().Type())
+			if err := build(s.shards[name]).Context(ctx).Exec(result.Interface()); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", name, err)
+				return
+			}
+			results[i] = result.Elem()
+		}(i, name)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	merged := rv.Elem()
+	for _, result := range results {
+		merged = reflect.AppendSlice(merged, result)
+	}
+	rv.Elem().Set(merged)
+	return nil
+}
+
+func (s *ShardedDB) Close() error {
+	var err error
+	for _, name := range s.names {
+		err = errors.Join(err, s.shards[name].Close())
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package quirk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharded(t *testing.T) {
+	dbs := make(map[string]*DB)
+	mocks := make(map[string]sqlmock.Sqlmock)
+	for _, name := range []string{"eu", "us"} {
+		sqlDB, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+		dbs[name] = wrapConnection(sqlDB, Postgres)
+		mocks[name] = mock
+	}
+	sharded, err := NewSharded(
+		dbs, func(key any) string {
+			return key.(string)
+		},
+	)
+	assert.Nil(t, err)
+	t.Run(
+		"route by key", func(t *testing.T) {
+			mocks["us"].ExpectQuery(`SELECT name FROM tenants;`).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("acme"))
+			var name string
+			assert.Nil(t, sharded.MustFor("us").Q(`SELECT name FROM tenants`).Exec(&name))
+			assert.Equal(t, "acme", name)
+			db, err := sharded.For("asia")
+			assert.Nil(t, db)
+			assert.ErrorIs(t, err, ErrorInvalidShard)
+			assert.Panics(
+				t, func() {
+					sharded.MustFor("asia")
+				},
+			)
+		},
+	)
+	t.Run(
+		"fan out", func(t *testing.T) {
+			mocks["eu"].ExpectQuery(`SELECT id FROM tenants;`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+			mocks["us"].ExpectQuery(`SELECT id FROM tenants;`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+			ids := make([]int, 0)
+			assert.Nil(
+				t, sharded.FanOut(
+					context.Background(), &ids, func(db *DB) *Quirk {
+						return db.Q(`SELECT id FROM tenants`)
+					},
+				),
+			)
+			assert.Equal(t, []int{1, 2, 3}, ids)
+			assert.ErrorIs(t, sharded.FanOut(context.Background(), ids, nil), ErrorInvalidDestination)
+		},
+	)
+	t.Run(
+		"invalid configuration", func(t *testing.T) {
+			_, err := NewSharded(nil, HashRouter())
+			assert.ErrorIs(t, err, ErrorInvalidShard)
+			_, err = NewSharded(dbs, nil)
+			assert.ErrorIs(t, err, ErrorInvalidShard)
+			_, err = NewSharded(map[string]*DB{"eu": nil}, HashRouter("eu"))
+			assert.ErrorIs(t, err, ErrorInvalidShard)
+		},
+	)
+	t.Run(
+		"hash router", func(t *testing.T) {
+			router := HashRouter("a", "b", "c")
+			assert.Equal(t, router(42), router(42))
+			assert.Contains(t, []string{"a", "b", "c"}, router("tenant"))
+			assert.Equal(t, "", HashRouter()("tenant"))
+		},
+	)
+}
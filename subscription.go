@@ -1,5 +1,109 @@
 package quirk
 
-import "time"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
 
-type subscription func(query string, duration time.Duration)
+type Subscription func(event QueryEvent)
+
+type QueryEvent struct {
+	Query    string
+	Args     []any
+	Sql      string
+	Duration time.Duration
+	Rows     int
+	Err      error
+	Dialect  string
+	TxDepth  int
+	Caller   string
+	Plan     json.RawMessage
+}
+
+const (
+	callerMaxDepth = 32
+)
+
+var (
+	modulePath = getModulePath()
+)
+
+func (d *DB) Subscribe(s Subscription) {
+	d.subscriptions = append(d.subscriptions, s)
+}
+
+func (d *DB) createQueryEvent(t time.Time, query string, args []any, rows int, err error) QueryEvent {
+	return QueryEvent{
+		Query:    query,
+		Args:     args,
+		Duration: time.Now().Sub(t),
+		Rows:     rows,
+		Err:      err,
+		Dialect:  d.driverName,
+		TxDepth:  d.txDepth,
+	}
+}
+
+func (d *DB) hasQueryConsumers(ctx context.Context, subscriptions ...Subscription) bool {
+	return len(d.subscriptions) > 0 || len(subscriptions) > 0 || d.logger != nil || GetQueryBudget(ctx) != nil
+}
+
+func (d *DB) publish(event QueryEvent, subscriptions ...Subscription) {
+	if len(d.subscriptions) > 0 || len(subscriptions) > 0 {
+		event.Sql = createQueryLog(d.driverName, event.Query, event.Args...)
+		event.Caller = getCaller()
+	}
+	for _, sub := range d.subscriptions {
+		sub(event)
+	}
+	for _, sub := range subscriptions {
+		sub(event)
+	}
+	d.logQuery(event)
+}
+
+func getCaller() string {
+	frame, ok := getCallerFrame()
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line)
+}
+
+func getCallerFrame() (runtime.Frame, bool) {
+	pc := make([]uintptr, callerMaxDepth)
+	n := runtime.Callers(2, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	for {
+		frame, more := frames.Next()
+		if !isModuleFunction(frame.Function) {
+			return frame, true
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+func isModuleFunction(function string) bool {
+	pkg := getFunctionPackage(function)
+	return pkg == modulePath || strings.HasPrefix(pkg, modulePath+"/")
+}
+
+func getFunctionPackage(function string) string {
+	slash := strings.LastIndex(function, "/")
+	dot := strings.Index(function[slash+1:], ".")
+	if dot < 0 {
+		return function
+	}
+	return function[:slash+1+dot]
+}
+
+func getModulePath() string {
+	pc, _, _, _ := runtime.Caller(0)
+	return getFunctionPackage(runtime.FuncForPC(pc).Name())
+}
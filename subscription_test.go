@@ -0,0 +1,75 @@
+package quirk
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscription(t *testing.T) {
+	t.Run(
+		"db subscription applies to every quirk", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			events := make([]QueryEvent, 0)
+			db.Subscribe(
+				func(event QueryEvent) {
+					events = append(events, event)
+				},
+			)
+			q := New(db).Q(`SELECT name FROM tests WHERE id = @id`, Map{"id": 1})
+			mock.ExpectQuery(q.CreateMatcher()).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("a").AddRow("b"))
+			names := make([]string, 0)
+			assert.Nil(t, q.Exec(&names))
+			failing := db.Q(`DELETE FROM tests`)
+			mock.ExpectQuery(failing.CreateMatcher()).WillReturnError(errors.New("boom"))
+			assert.NotNil(t, failing.Exec())
+			assert.Len(t, events, 2)
+			assert.Equal(t, "SELECT name FROM tests WHERE id = $1;", events[0].Query)
+			assert.Equal(t, "SELECT name FROM tests WHERE id = 1;", events[0].Sql)
+			assert.Equal(t, []any{1}, events[0].Args)
+			assert.Equal(t, 2, events[0].Rows)
+			assert.Equal(t, Postgres, events[0].Dialect)
+			assert.Equal(t, 0, events[0].TxDepth)
+			assert.NotEmpty(t, events[0].Caller)
+			assert.EqualError(t, events[1].Err, "boom")
+		},
+	)
+	t.Run(
+		"transaction depth", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			var depth int
+			db.Subscribe(
+				func(event QueryEvent) {
+					depth = event.TxDepth
+				},
+			)
+			mock.ExpectQuery("BEGIN;").WillReturnRows(sqlmock.NewRows(nil))
+			tx, err := db.Begin()
+			assert.Nil(t, err)
+			assert.Equal(t, 1, depth)
+			q := New(tx).Q(`SELECT 1`)
+			mock.ExpectQuery(q.CreateMatcher()).WillReturnRows(sqlmock.NewRows(nil))
+			assert.Nil(t, q.Exec())
+			assert.Equal(t, 1, depth)
+		},
+	)
+	t.Run(
+		"query consumers", func(t *testing.T) {
+			sqlDB, _, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			assert.False(t, db.hasQueryConsumers(context.Background()))
+			assert.True(t, db.hasQueryConsumers(WithQueryBudget(context.Background())))
+			assert.True(t, db.hasQueryConsumers(context.Background(), func(event QueryEvent) {}))
+			db.Log()
+			assert.True(t, db.hasQueryConsumers(context.Background()))
+		},
+	)
+}
@@ -0,0 +1,98 @@
+package quirk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	pg "github.com/lib/pq"
+)
+
+type localTimeouts struct {
+	statement time.Duration
+	lock      time.Duration
+}
+
+const (
+	pgErrorQueryCanceled     = "57014"
+	pgErrorLockNotAvailable  = "55P03"
+	statementTimeoutParam    = "statement_timeout"
+	lockTimeoutParam         = "lock_timeout"
+	setLocalStatementTimeout = "SET LOCAL statement_timeout = %d"
+	setLocalLockTimeout      = "SET LOCAL lock_timeout = %d"
+	resetLocalTimeout        = "SET LOCAL %s TO DEFAULT"
+)
+
+func (q *Quirk) Timeout(timeout time.Duration) *Quirk {
+	q.timeout = timeout
+	return q
+}
+
+func (d *DB) StatementTimeout(timeout time.Duration) {
+	d.statementTimeout = timeout
+}
+
+func (d *DB) LockTimeout(timeout time.Duration) {
+	d.lockTimeout = timeout
+}
+
+func (q *Quirk) applyTimeout(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	timeout := q.statementTimeout
+	if q.timeout > 0 {
+		timeout = q.timeout
+	}
+	cancel := func() {}
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	if !q.transaction || q.conn == nil || q.driverName != Postgres {
+		return ctx, cancel, nil
+	}
+	if timeout != q.localTimeouts.statement {
+		if err := q.setLocalTimeout(ctx, statementTimeoutParam, setLocalStatementTimeout, timeout); err != nil {
+			cancel()
+			return ctx, cancel, wrapTimeoutError(ctx, err)
+		}
+		q.localTimeouts.statement = timeout
+	}
+	if q.lockTimeout != q.localTimeouts.lock {
+		if err := q.setLocalTimeout(ctx, lockTimeoutParam, setLocalLockTimeout, q.lockTimeout); err != nil {
+			cancel()
+			return ctx, cancel, wrapTimeoutError(ctx, err)
+		}
+		q.localTimeouts.lock = q.lockTimeout
+	}
+	return ctx, cancel, nil
+}
+
+func (d *DB) setLocalTimeout(ctx context.Context, param, statement string, timeout time.Duration) error {
+	if timeout > 0 {
+		statement = fmt.Sprintf(statement, timeout.Milliseconds())
+	} else {
+		statement = fmt.Sprintf(resetLocalTimeout, param)
+	}
+	_, err := d.conn.ExecContext(ctx, statement)
+	return err
+}
+
+func wrapTimeoutError(ctx context.Context, err error) error {
+	if err == nil || errors.Is(err, ErrQueryCanceled) {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return fmt.Errorf("%w: %w", ErrQueryCanceled, err)
+	}
+	if ctx != nil && ctx.Err() != nil {
+		return fmt.Errorf("%w: %w: %w", ErrQueryCanceled, ctx.Err(), err)
+	}
+	var pgErr *pg.Error
+	if errors.As(err, &pgErr) && (pgErr.Code == pgErrorQueryCanceled || pgErr.Code == pgErrorLockNotAvailable) {
+		return fmt.Errorf("%w: %w", ErrQueryCanceled, err)
+	}
+	return err
+}
+
+func formatTimeoutParam(timeout time.Duration) string {
+	return fmt.Sprintf("%d", timeout.Milliseconds())
+}
@@ -0,0 +1,152 @@
+package quirk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pg "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	t.Run(
+		"query deadline", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			q := db.Q(`SELECT pg_sleep(10)`).Timeout(5 * time.Millisecond)
+			mock.ExpectQuery(q.CreateMatcher()).WillDelayFor(time.Second).WillReturnRows(sqlmock.NewRows(nil))
+			err = q.Exec()
+			assert.ErrorIs(t, err, ErrQueryCanceled)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		},
+	)
+	inTransaction := func(t *testing.T, db *DB, mock sqlmock.Sqlmock, fn func(tx *DB)) {
+		mock.ExpectQuery("BEGIN;").WillReturnRows(sqlmock.NewRows(nil))
+		assert.Nil(
+			t, db.Session(
+				context.Background(), func(s *DB) error {
+					fn(s.MustBegin())
+					return nil
+				},
+			),
+		)
+	}
+	t.Run(
+		"set local inside transaction", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			db.StatementTimeout(time.Second)
+			inTransaction(
+				t, db, mock, func(tx *DB) {
+					q := tx.Q(`SELECT 1`).Timeout(2 * time.Second)
+					mock.ExpectExec("SET LOCAL statement_timeout = 2000").WillReturnResult(sqlmock.NewResult(0, 0))
+					mock.ExpectQuery(q.CreateMatcher()).WillReturnRows(sqlmock.NewRows(nil))
+					mock.ExpectExec(postgresDiscardAll).WillReturnResult(sqlmock.NewResult(0, 0))
+					assert.Nil(t, q.Exec())
+				},
+			)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"local timeout reset for next statement", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			inTransaction(
+				t, db, mock, func(tx *DB) {
+					first := tx.Q(`SELECT 1`).Timeout(100 * time.Millisecond)
+					mock.ExpectExec("SET LOCAL statement_timeout = 100").WillReturnResult(sqlmock.NewResult(0, 0))
+					mock.ExpectQuery(first.CreateMatcher()).WillReturnRows(sqlmock.NewRows(nil))
+					assert.Nil(t, first.Exec())
+					second := tx.Q(`SELECT 2`)
+					mock.ExpectExec("SET LOCAL statement_timeout TO DEFAULT").WillReturnResult(sqlmock.NewResult(0, 0))
+					mock.ExpectQuery(second.CreateMatcher()).WillReturnRows(sqlmock.NewRows(nil))
+					assert.Nil(t, second.Exec())
+					third := tx.Q(`SELECT 3`)
+					mock.ExpectQuery(third.CreateMatcher()).WillReturnRows(sqlmock.NewRows(nil))
+					mock.ExpectExec(postgresDiscardAll).WillReturnResult(sqlmock.NewResult(0, 0))
+					assert.Nil(t, third.Exec())
+				},
+			)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"lock timeout inside transaction", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			db.StatementTimeout(5 * time.Second)
+			db.LockTimeout(time.Second)
+			inTransaction(
+				t, db, mock, func(tx *DB) {
+					q := tx.Q(`UPDATE tests SET name = 'a'`)
+					mock.ExpectExec("SET LOCAL statement_timeout = 5000").WillReturnResult(sqlmock.NewResult(0, 0))
+					mock.ExpectExec("SET LOCAL lock_timeout = 1000").WillReturnResult(sqlmock.NewResult(0, 0))
+					mock.ExpectQuery(q.CreateMatcher()).WillReturnRows(sqlmock.NewRows(nil))
+					assert.Nil(t, q.Exec())
+					next := tx.Q(`UPDATE tests SET name = 'b'`)
+					mock.ExpectQuery(next.CreateMatcher()).WillReturnRows(sqlmock.NewRows(nil))
+					mock.ExpectExec(postgresDiscardAll).WillReturnResult(sqlmock.NewResult(0, 0))
+					assert.Nil(t, next.Exec())
+				},
+			)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"lock timeout without statement timeout", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			db.LockTimeout(time.Second)
+			inTransaction(
+				t, db, mock, func(tx *DB) {
+					q := tx.Q(`UPDATE tests SET name = 'a'`)
+					mock.ExpectExec("SET LOCAL lock_timeout = 1000").WillReturnResult(sqlmock.NewResult(0, 0))
+					mock.ExpectQuery(q.CreateMatcher()).WillReturnRows(sqlmock.NewRows(nil))
+					mock.ExpectExec(postgresDiscardAll).WillReturnResult(sqlmock.NewResult(0, 0))
+					assert.Nil(t, q.Exec())
+				},
+			)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"no set local without pinned connection", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			db.LockTimeout(time.Second)
+			mock.ExpectQuery("BEGIN;").WillReturnRows(sqlmock.NewRows(nil))
+			tx := db.MustBegin()
+			q := tx.Q(`SELECT 1`).Timeout(time.Second)
+			mock.ExpectQuery(q.CreateMatcher()).WillReturnRows(sqlmock.NewRows(nil))
+			assert.Nil(t, q.Exec())
+			assert.Nil(t, mock.ExpectationsWereMet())
+		},
+	)
+	t.Run(
+		"server cancellation", func(t *testing.T) {
+			err := wrapTimeoutError(context.Background(), &pg.Error{Code: pgErrorQueryCanceled})
+			assert.ErrorIs(t, err, ErrQueryCanceled)
+		},
+	)
+	t.Run(
+		"session timeouts in data source", func(t *testing.T) {
+			_, dataSource, _, err := createConnectionDataSource(
+				WithHost("localhost"),
+				WithStatementTimeout(5*time.Second),
+				WithLockTimeout(time.Second),
+				WithPostgres(),
+			)
+			assert.Nil(t, err)
+			assert.Equal(t, "host=localhost statement_timeout=5000 lock_timeout=1000", dataSource)
+		},
+	)
+}
@@ -0,0 +1,144 @@
+package quirk
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+type tlsDialer struct {
+	dialer net.Dialer
+	config *tls.Config
+}
+
+const (
+	paramSslcert   = "sslcert"
+	paramSslkey    = "sslkey"
+	paramSslinline = "sslinline"
+)
+
+const (
+	sslRequestCode     = 80877103
+	sslRequestLength   = 8
+	sslResponseAllowed = 'S'
+)
+
+func (d *tlsDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+func (d *tlsDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d.DialContext(ctx, network, address)
+}
+
+func (d *tlsDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn, err := d.upgrade(ctx, conn, address)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func (d *tlsDialer) upgrade(ctx context.Context, conn net.Conn, address string) (net.Conn, error) {
+	request := make([]byte, sslRequestLength)
+	binary.BigEndian.PutUint32(request[0:4], sslRequestLength)
+	binary.BigEndian.PutUint32(request[4:8], sslRequestCode)
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+	response := make([]byte, 1)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+	if response[0] != sslResponseAllowed {
+		return nil, ErrorSslNotSupported
+	}
+	config := d.config.Clone()
+	if len(config.ServerName) == 0 {
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			config.ServerName = host
+		}
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func validateClientCert(cert, key []byte, source string) error {
+	if _, err := tls.X509KeyPair(cert, key); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrorInvalidCertificate, source, err)
+	}
+	return nil
+}
+
+func validateRootCert(cert []byte, source string) error {
+	if !x509.NewCertPool().AppendCertsFromPEM(cert) {
+		return fmt.Errorf("%w: %s: no PEM certificates found", ErrorInvalidCertificate, source)
+	}
+	return nil
+}
+
+func readCertFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrorInvalidCertificate, err)
+	}
+	return b, nil
+}
+
+func validateCertFiles(props *dataSource) error {
+	inline, _ := props.get(paramSslinline)
+	cert, certOk := props.get(paramSslcert)
+	key, keyOk := props.get(paramSslkey)
+	root, rootOk := props.get(paramSslrootcert)
+	if certOk != keyOk {
+		return fmt.Errorf("%w: client certificate and key must be set together", ErrorInvalidCertificate)
+	}
+	if inline == "true" {
+		if certOk {
+			if err := validateClientCert([]byte(cert), []byte(key), "inline client certificate"); err != nil {
+				return err
+			}
+		}
+		if rootOk {
+			return validateRootCert([]byte(root), "inline root certificate")
+		}
+		return nil
+	}
+	if certOk {
+		certPem, err := readCertFile(cert)
+		if err != nil {
+			return err
+		}
+		keyPem, err := readCertFile(key)
+		if err != nil {
+			return err
+		}
+		if err := validateClientCert(certPem, keyPem, cert); err != nil {
+			return err
+		}
+	}
+	if rootOk {
+		rootPem, err := readCertFile(root)
+		if err != nil {
+			return err
+		}
+		return validateRootCert(rootPem, root)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package quirk
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestCertificate(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestTls(t *testing.T) {
+	certPem, keyPem := createTestCertificate(t)
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	assert.Nil(t, os.WriteFile(certPath, certPem, 0600))
+	assert.Nil(t, os.WriteFile(keyPath, keyPem, 0600))
+	t.Run(
+		"certificate files", func(t *testing.T) {
+			_, dataSource, _, err := createConnectionDataSource(
+				WithPostgres(), WithRootCert(certPath), WithClientCert(certPath, keyPath), WithSslVerifyFull(),
+			)
+			assert.Nil(t, err)
+			assert.Equal(
+				t,
+				"sslrootcert="+certPath+" sslcert="+certPath+" sslkey="+keyPath+" sslmode=verify-full",
+				dataSource,
+			)
+		},
+	)
+	t.Run(
+		"inline certificates", func(t *testing.T) {
+			_, dataSource, _, err := createConnectionDataSource(WithPostgres(), WithRootCertPEM(certPem))
+			assert.Nil(t, err)
+			assert.Contains(t, dataSource, "sslinline=true")
+			assert.Contains(t, dataSource, "sslrootcert='-----BEGIN CERTIFICATE-----")
+		},
+	)
+	t.Run(
+		"invalid certificates", func(t *testing.T) {
+			_, _, _, err := createConnectionDataSource(WithRootCert(filepath.Join(dir, "missing.crt")))
+			assert.ErrorIs(t, err, ErrorInvalidCertificate)
+			_, _, _, err = createConnectionDataSource(WithClientCert(certPath, certPath))
+			assert.ErrorIs(t, err, ErrorInvalidCertificate)
+			_, _, _, err = createConnectionDataSource(WithRootCertPEM([]byte("garbage")))
+			assert.ErrorIs(t, err, ErrorInvalidCertificate)
+			_, _, _, err = createConnectionDataSource(WithRootCert(certPath), WithRootCertPEM(certPem))
+			assert.ErrorIs(t, err, ErrorInvalidCertificate)
+		},
+	)
+	t.Run(
+		"custom tls config dialer", func(t *testing.T) {
+			cert, err := tls.X509KeyPair(certPem, keyPem)
+			assert.Nil(t, err)
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			assert.Nil(t, err)
+			defer func() {
+				_ = listener.Close()
+			}()
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				request := make([]byte, sslRequestLength)
+				if _, err := io.ReadFull(conn, request); err != nil {
+					return
+				}
+				_, _ = conn.Write([]byte{sslResponseAllowed})
+				tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+				_ = tlsConn.Handshake()
+				_, _ = tlsConn.Write([]byte("ok"))
+			}()
+			pool := x509.NewCertPool()
+			pool.AppendCertsFromPEM(certPem)
+			dialer := &tlsDialer{config: &tls.Config{RootCAs: pool}}
+			conn, err := dialer.DialContext(context.Background(), "tcp", listener.Addr().String())
+			assert.Nil(t, err)
+			response := make([]byte, 2)
+			_, err = io.ReadFull(conn, response)
+			assert.Nil(t, err)
+			assert.Equal(t, "ok", string(response))
+			_, dataSource, _, err := createConnectionDataSource(
+				WithPostgres(), WithTLSConfig(&tls.Config{RootCAs: pool}),
+			)
+			assert.Nil(t, err)
+			assert.Equal(t, "sslmode=disable", dataSource)
+			_, _, _, err = createConnectionDataSource(
+				WithPostgres(), WithSslVerifyFull(), WithTLSConfig(&tls.Config{RootCAs: pool}),
+			)
+			assert.ErrorIs(t, err, ErrorInvalidConfig)
+			_, _, _, err = createConnectionDataSource(
+				WithURL("postgres://localhost/app?sslmode=require"), WithTLSConfig(&tls.Config{RootCAs: pool}),
+			)
+			assert.ErrorIs(t, err, ErrorInvalidConfig)
+		},
+	)
+}
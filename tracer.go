@@ -0,0 +1,139 @@
+package quirk
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	End(err error)
+}
+
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+type RecordingTracer struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+type RecordedSpan struct {
+	Id         int
+	ParentId   int
+	Name       string
+	Attributes map[string]any
+	Err        error
+	StartedAt  time.Time
+	EndedAt    time.Time
+	tracer     *RecordingTracer
+}
+
+type noopSpan struct{}
+
+type recordedSpanKey struct{}
+
+const (
+	AttributeDbSystem    = "db.system"
+	AttributeDbStatement = "db.statement"
+	AttributeDbOperation = "db.operation"
+	AttributeDbRows      = "db.rows"
+)
+
+const (
+	dbSystemPostgres = "postgresql"
+)
+
+func (d *DB) Tracer(tracer Tracer) {
+	d.tracer = tracer
+}
+
+func (d *DB) StartSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	if d.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	system := d.driverName
+	if system == Postgres {
+		system = dbSystemPostgres
+	}
+	return d.tracer.Start(ctx, name, append([]Attribute{{Key: AttributeDbSystem, Value: system}}, attrs...)...)
+}
+
+func (d *DB) startQuerySpan(ctx context.Context, query string) (context.Context, Span) {
+	operation := getOperation(query)
+	return d.StartSpan(
+		ctx, operation,
+		Attribute{Key: AttributeDbStatement, Value: formatSql(query)},
+		Attribute{Key: AttributeDbOperation, Value: operation},
+	)
+}
+
+func NewRecordingTracer() *RecordingTracer {
+	return &RecordingTracer{spans: make([]*RecordedSpan, 0)}
+}
+
+func (t *RecordingTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &RecordedSpan{
+		Id:         len(t.spans) + 1,
+		Name:       name,
+		Attributes: make(map[string]any),
+		StartedAt:  time.Now(),
+		tracer:     t,
+	}
+	if parent, ok := ctx.Value(recordedSpanKey{}).(*RecordedSpan); ok {
+		span.ParentId = parent.Id
+	}
+	for _, attr := range attrs {
+		span.Attributes[attr.Key] = attr.Value
+	}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, recordedSpanKey{}, span), span
+}
+
+func (t *RecordingTracer) Spans() []RecordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := make([]RecordedSpan, len(t.spans))
+	for i, span := range t.spans {
+		result[i] = *span
+		result[i].Attributes = make(map[string]any, len(span.Attributes))
+		for k, v := range span.Attributes {
+			result[i].Attributes[k] = v
+		}
+	}
+	return result
+}
+
+func (t *RecordingTracer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = make([]*RecordedSpan, 0)
+}
+
+func (s *RecordedSpan) SetAttributes(attrs ...Attribute) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	for _, attr := range attrs {
+		s.Attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *RecordedSpan) End(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.Err = err
+	s.EndedAt = time.Now()
+}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+
+func (noopSpan) End(error) {}
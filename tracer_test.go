@@ -0,0 +1,60 @@
+package quirk
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracer(t *testing.T) {
+	t.Run(
+		"query spans", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			tracer := NewRecordingTracer()
+			db.Tracer(tracer)
+			ctx, parent := tracer.Start(context.Background(), "request")
+			q := db.Q(`SELECT id FROM tests WHERE id = @id`, Map{"id": 1}).Context(ctx)
+			mock.ExpectQuery(q.CreateMatcher()).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			var id int
+			assert.Nil(t, q.Exec(&id))
+			parent.End(nil)
+			failing := db.Q(`DELETE FROM tests`)
+			mock.ExpectQuery(failing.CreateMatcher()).WillReturnError(errors.New("boom"))
+			assert.NotNil(t, failing.Exec())
+			spans := tracer.Spans()
+			assert.Len(t, spans, 3)
+			assert.Equal(t, "SELECT", spans[1].Name)
+			assert.Equal(t, spans[0].Id, spans[1].ParentId)
+			assert.Equal(t, "postgresql", spans[1].Attributes[AttributeDbSystem])
+			assert.Equal(t, "SELECT", spans[1].Attributes[AttributeDbOperation])
+			assert.Equal(t, "SELECT id FROM tests WHERE id = $1;", spans[1].Attributes[AttributeDbStatement])
+			assert.Equal(t, 1, spans[1].Attributes[AttributeDbRows])
+			assert.Nil(t, spans[1].Err)
+			assert.False(t, spans[1].EndedAt.IsZero())
+			assert.Equal(t, 0, spans[2].ParentId)
+			assert.EqualError(t, spans[2].Err, "boom")
+		},
+	)
+	t.Run(
+		"transaction spans", func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			assert.Nil(t, err)
+			db := wrapConnection(sqlDB, Postgres)
+			tracer := NewRecordingTracer()
+			db.Tracer(tracer)
+			mock.ExpectQuery("BEGIN;").WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery("COMMIT;").WillReturnRows(sqlmock.NewRows(nil))
+			tx := db.MustBegin()
+			tx.MustCommit()
+			spans := tracer.Spans()
+			assert.Len(t, spans, 2)
+			assert.Equal(t, "BEGIN", spans[0].Name)
+			assert.Equal(t, "COMMIT", spans[1].Name)
+		},
+	)
+}
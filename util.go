@@ -146,3 +146,22 @@ func RemoveAccents(value string) string {
 func Escape(value string) string {
 	return escaper.Replace(value)
 }
+
+func getOperation(query string) string {
+	query = strings.TrimSpace(query)
+	for strings.HasPrefix(query, "/*") || strings.HasPrefix(query, "--") {
+		end, n := strings.Index(query, "*/"), 2
+		if strings.HasPrefix(query, "--") {
+			end, n = strings.Index(query, "\n"), 1
+		}
+		if end < 0 {
+			return ""
+		}
+		query = strings.TrimSpace(query[end+n:])
+	}
+	fields := strings.Fields(strings.TrimLeft(query, "( \t\n"))
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(strings.TrimSuffix(fields[0], querySuffix))
+}